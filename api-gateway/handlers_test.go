@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -150,4 +153,102 @@ func TestGetEnvFunction(t *testing.T) {
 			assert.Equal(t, tt.expected, result)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestProxyStreamsEventStreams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: seats\ndata: {\"seatIds\":[1]}\n\n"))
+		w.(http.Flusher).Flush()
+
+		// Keep the stream open until the test has read the event.
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+
+	router := gin.New()
+	router.Any("/api/cinema/*path", proxyHandler(upstream.URL))
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	// Ending the upstream stream first lets the servers shut down even
+	// when the assertions below fail.
+	defer close(release)
+
+	// Without flushing the gateway would not even send the headers, so
+	// the whole request runs under the timeout below.
+	lines := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(gateway.URL + "/api/cinema/showtimes/1/seats/events")
+		if err != nil {
+			lines <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		lines <- resp.Header.Get("Content-Type") + " " + line
+	}()
+
+	select {
+	case line := <-lines:
+		assert.Equal(t, "text/event-stream event: seats\n", line)
+	case <-time.After(2 * time.Second):
+		t.Fatal("event was buffered by the gateway")
+	}
+}
+
+func TestProxyPassesWebSocketUpgrades(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.URL.Path != "/api/cinema/showtimes/1/seats/ws" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+
+		// Echo one line so the test can tell the tunnel is open both ways.
+		line, _ := rw.ReadString('\n')
+		rw.WriteString("echo " + line)
+		rw.Flush()
+	}))
+	defer upstream.Close()
+
+	router := gin.New()
+	router.Any("/api/cinema/*path", proxyHandler(upstream.URL))
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	conn, err := net.Dial("tcp", gateway.Listener.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	conn.Write([]byte("GET /api/cinema/showtimes/1/seats/ws HTTP/1.1\r\nHost: gateway\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	conn.Write([]byte("hello\n"))
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "echo hello\n", line)
+}
@@ -4,6 +4,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"os"
 	"strings"
 
@@ -87,13 +89,26 @@ func proxyHandler(targetURL string) gin.HandlerFunc {
 			path = strings.TrimPrefix(originalPath, "/api/booking")
 		}
 		
+		// WebSocket upgrades need the connection handed over as a whole,
+		// which the reverse proxy does once the upstream switches protocols
+		if isUpgradeRequest(c.Request) {
+			target, err := url.Parse(targetURL)
+			if err != nil {
+				c.JSON(500, gin.H{"error": "Failed to create request"})
+				return
+			}
+			httputil.NewSingleHostReverseProxy(target).ServeHTTP(c.Writer, c.Request)
+			return
+		}
+
 		targetPath := targetURL + "/api" + strings.TrimPrefix(originalPath, "/api")
 		if c.Request.URL.RawQuery != "" {
 			targetPath += "?" + c.Request.URL.RawQuery
 		}
 
-		// Create new request
-		req, err := http.NewRequest(c.Request.Method, targetPath, c.Request.Body)
+		// Create new request, cancelled when the client goes away so open
+		// event streams upstream are closed too
+		req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, targetPath, c.Request.Body)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "Failed to create request"})
 			return
@@ -124,10 +139,48 @@ func proxyHandler(targetURL string) gin.HandlerFunc {
 
 		// Copy response body
 		c.Status(resp.StatusCode)
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			streamBody(c.Writer, resp.Body)
+			return
+		}
 		io.Copy(c.Writer, resp.Body)
 	}
 }
 
+// isUpgradeRequest reports whether the client asks to switch protocols,
+// as WebSocket handshakes do.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamBody relays an event stream to the client as it arrives, flushing
+// after every read instead of buffering until the upstream closes.
+func streamBody(w gin.ResponseWriter, body io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			w.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -23,7 +23,7 @@ func Init() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	err = DB.AutoMigrate(&models.Booking{})
+	err = DB.AutoMigrate(&models.Booking{}, &models.BookingItem{}, &models.PromoCode{}, &models.PromoRedemption{}, &models.Payment{}, &models.BookingStatusChange{}, &models.ScanEvent{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -3,12 +3,16 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"booking-service/models"
 	"booking-service/services"
+	"booking-service/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,15 +26,15 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		token := strings.Replace(authHeader, "Bearer ", "", 1)
-		
+
 		reqBody := map[string]string{"token": token}
 		jsonData, _ := json.Marshal(reqBody)
-		
+
 		authServiceURL := os.Getenv("AUTH_SERVICE_URL")
 		if authServiceURL == "" {
 			authServiceURL = "http://localhost:3001"
 		}
-		
+
 		resp, err := http.Post(authServiceURL+"/api/auth/verify", "application/json", bytes.NewBuffer(jsonData))
 		if err != nil || resp.StatusCode != 200 {
 			c.JSON(401, gin.H{"error": "Invalid token"})
@@ -41,7 +45,7 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		var result struct {
 			User  models.User `json:"user"`
-			Valid bool `json:"valid"`
+			Valid bool        `json:"valid"`
 		}
 		json.NewDecoder(resp.Body).Decode(&result)
 
@@ -56,6 +60,17 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// bookingResponse describes a booking that was made or changed, listing
+// the single seats its new seats strand in studios that only warn about
+// them.
+func bookingResponse(booking *models.Booking, gapWarnings []utils.SeatGap) gin.H {
+	response := gin.H{"booking": booking, "qrCode": booking.QRCode}
+	if len(gapWarnings) > 0 {
+		response["gapWarnings"] = gapWarnings
+	}
+	return response
+}
+
 func CreateOnlineBooking(c *gin.Context) {
 	var req models.OnlineBookingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -66,13 +81,13 @@ func CreateOnlineBooking(c *gin.Context) {
 	user, _ := c.Get("user")
 	userObj := user.(models.User)
 
-	booking, err := services.CreateOnlineBooking(req, userObj)
+	booking, gapWarnings, err := services.CreateOnlineBooking(req, userObj)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondBookingError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"booking": booking, "qrCode": booking.QRCode})
+	c.JSON(http.StatusCreated, bookingResponse(booking, gapWarnings))
 }
 
 func CreateOfflineBooking(c *gin.Context) {
@@ -82,36 +97,83 @@ func CreateOfflineBooking(c *gin.Context) {
 		return
 	}
 
-	booking, err := services.CreateOfflineBooking(req)
+	booking, gapWarnings, err := services.CreateOfflineBooking(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondBookingError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"booking": booking, "qrCode": booking.QRCode})
+	c.JSON(http.StatusCreated, bookingResponse(booking, gapWarnings))
 }
 
-func ValidateQRCode(c *gin.Context) {
-	var req models.ValidateQRRequest
+// QuoteTickets prices seats for a showtime without booking them.
+func QuoteTickets(c *gin.Context) {
+	var req models.PriceQuoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
-	booking, err := services.ValidateQRCode(req.BookingCode)
+	quote, err := services.QuoteTickets(req)
 	if err != nil {
-		if err.Error() == "invalid or used ticket" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// respondBookingError reports a failed booking, passing on which seats were
+// at fault when cinema service refused them.
+func respondBookingError(c *gin.Context, err error) {
+	var invalid *utils.SeatRequestError
+	var conflict *utils.SeatConflictError
+	switch {
+	case errors.Is(err, services.ErrInvalidCustomerCategory),
+		errors.Is(err, services.ErrTicketSeatNotBooked),
+		errors.Is(err, services.ErrDuplicateTicket),
+		errors.Is(err, services.ErrPromoNotFound),
+		errors.Is(err, services.ErrPromoNotActive),
+		errors.Is(err, services.ErrPromoNotApplicable),
+		errors.Is(err, services.ErrPromoFirstBooking):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPromoExhausted),
+		errors.Is(err, services.ErrPromoUserLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentsUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.As(err, &invalid):
+		response := gin.H{"error": invalid.Error()}
+		if invalid.SeatIDs != nil {
+			response["seatIds"] = invalid.SeatIDs
 		}
+		c.JSON(http.StatusBadRequest, response)
+	case errors.As(err, &conflict):
+		conflicts := conflict.Seats
+		if conflicts == nil {
+			conflicts = []utils.SeatConflict{}
+		}
+		response := gin.H{"error": conflict.Error(), "conflicts": conflicts}
+		if len(conflict.Gaps) > 0 {
+			response["gaps"] = conflict.Gaps
+		}
+		c.JSON(http.StatusConflict, response)
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func ValidateQRCode(c *gin.Context) {
+	var req models.ValidateQRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
-	// Convert pq.Int64Array back to []uint for response
-	seatIDs := make([]uint, len(booking.SeatIDs))
-	for i, id := range booking.SeatIDs {
-		seatIDs[i] = uint(id)
+	booking, admitted, err := services.ValidateQRCode(req, staffUser(c))
+	if err != nil {
+		respondAdmissionError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -119,13 +181,124 @@ func ValidateQRCode(c *gin.Context) {
 		"booking": gin.H{
 			"bookingCode":  booking.BookingCode,
 			"studioId":     booking.StudioID,
-			"seatIds":      seatIDs,
+			"seatIds":      bookingSeatIDs(booking),
 			"customerName": booking.UserName,
 			"bookingType":  booking.BookingType,
 		},
+		"admittedSeatIds": admitted,
+		"admittedCount":   admittedCount(booking),
+		"seatCount":       len(booking.SeatIDs),
 	})
 }
 
+// ExitScan scans seats out at the entrance so they can get in again.
+func ExitScan(c *gin.Context) {
+	var req models.ValidateQRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	booking, left, err := services.RecordExit(req, staffUser(c))
+	if err != nil {
+		respondAdmissionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookingCode":   booking.BookingCode,
+		"exitedSeatIds": left,
+		"admittedCount": admittedCount(booking),
+		"seatCount":     len(booking.SeatIDs),
+	})
+}
+
+// GetBookingScans shows staff every scan of a booking and how its seats
+// got in.
+func GetBookingScans(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	scans, err := services.GetBookingScans(uint(id))
+	if err != nil {
+		respondBookingChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scans)
+}
+
+// staffUser is the staff account a scan is made under, if the request was
+// authenticated.
+func staffUser(c *gin.Context) *models.User {
+	user, ok := c.Get("user")
+	if !ok {
+		return nil
+	}
+	userObj := user.(models.User)
+	return &userObj
+}
+
+// bookingSeatIDs converts a booking's pq.Int64Array back to []uint for
+// responses.
+func bookingSeatIDs(booking *models.Booking) []uint {
+	seatIDs := make([]uint, len(booking.SeatIDs))
+	for i, id := range booking.SeatIDs {
+		seatIDs[i] = uint(id)
+	}
+	return seatIDs
+}
+
+// admittedCount is how many seats of a booking ever got in. Bookings
+// without items count as wholly admitted once used.
+func admittedCount(booking *models.Booking) int {
+	if len(booking.Items) == 0 && booking.Status == models.BookingUsed {
+		return len(booking.SeatIDs)
+	}
+	count := 0
+	for _, item := range booking.Items {
+		if item.CancelledAt == nil && item.AdmittedAt != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// respondAdmissionError answers a refused or failed scan.
+func respondAdmissionError(c *gin.Context, err error) {
+	var refused *services.AdmissionError
+	if errors.As(err, &refused) {
+		c.JSON(admissionStatus(refused.Reason), gin.H{"valid": false, "error": refused.Message, "reason": refused.Reason})
+		return
+	}
+	if errors.Is(err, services.ErrStudioRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// admissionStatus is the HTTP status a refused ticket is answered with:
+// 400 for tickets that are not genuine or seats not on them, 404 for
+// unknown bookings and 409 for genuine tickets that may not be admitted
+// here and now.
+func admissionStatus(reason string) int {
+	switch reason {
+	case models.RejectMalformedTicket, models.RejectUnknownKey, models.RejectBadSignature, models.RejectSeatNotBooked:
+		return http.StatusBadRequest
+	case models.RejectNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusConflict
+}
+
 func GetUserBookings(c *gin.Context) {
 	user, _ := c.Get("user")
 	userObj := user.(models.User)
@@ -137,4 +310,161 @@ func GetUserBookings(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, bookings)
-}
\ No newline at end of file
+}
+
+// CancelBooking cancels a booking for its customer or for staff. The body
+// may give a reason.
+func CancelBooking(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var req models.CancelBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	userObj := user.(models.User)
+
+	booking, err := services.CancelBooking(uint(id), userObj, strings.TrimSpace(req.Reason))
+	if err != nil {
+		respondBookingChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"booking":      booking,
+		"refundPolicy": booking.RefundPolicy,
+		"refundAmount": booking.RefundAmount,
+	})
+}
+
+// CancelSeats drops some seats from a booking and answers with the booking
+// under its new code and QR code.
+func CancelSeats(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var req models.CancelSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	userObj := user.(models.User)
+
+	booking, err := services.CancelSeats(uint(id), userObj, req.SeatIDs)
+	if err != nil {
+		respondBookingChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"booking": booking, "qrCode": booking.QRCode})
+}
+
+// ExchangeSeats moves a booking to other seats and answers with the booking
+// under its new code and QR code.
+func ExchangeSeats(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var req models.ExchangeSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	userObj := user.(models.User)
+
+	booking, gapWarnings, err := services.ExchangeSeats(uint(id), userObj, req.SeatIDs, req.NewSeatIDs)
+	if err != nil {
+		respondBookingChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bookingResponse(booking, gapWarnings))
+}
+
+// GetBookingHistory lists the status changes of a booking for its customer
+// or for staff.
+func GetBookingHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	userObj := user.(models.User)
+
+	history, err := services.GetBookingHistory(uint(id), userObj)
+	if err != nil {
+		respondBookingChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// MarkNoShow records that nobody came for a booking.
+func MarkNoShow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	userObj := user.(models.User)
+
+	booking, err := services.MarkNoShow(uint(id), userObj)
+	if err != nil {
+		respondBookingChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// respondBookingChangeError maps errors from cancelling or changing a
+// booking to HTTP statuses.
+func respondBookingChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNoSeatsToCancel),
+		errors.Is(err, services.ErrSeatsNotInBooking),
+		errors.Is(err, services.ErrCancelEverySeat),
+		errors.Is(err, services.ErrExchangeSeatCount),
+		errors.Is(err, services.ErrDuplicateExchange),
+		errors.Is(err, services.ErrExchangeSeatKept):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBookingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRefundFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBookingForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, services.ErrShowtimeNotStarted),
+		errors.Is(err, services.ErrBookingNotCancellable),
+		errors.Is(err, services.ErrBookingNotExchangeable),
+		errors.Is(err, services.ErrBookingChanged),
+		errors.Is(err, services.ErrExchangeCostsMore),
+		errors.Is(err, services.ErrCancellationClosed),
+		errors.Is(err, services.ErrPaymentCapturing):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondBookingError(c, err)
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"booking-service/models"
+	"booking-service/services"
+	"booking-service/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -139,25 +142,69 @@ func TestCreateOfflineBookingHandler(t *testing.T) {
 func TestValidateQRCodeHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
+	elsewhere := utils.SignTicket(utils.Ticket{BookingCode: "BOOK123", StudioID: 5, ShowtimeID: 1, SeatIDs: []uint{1}})
+
+	var logged []models.ScanEvent
+	services.UseRefusedScanLog(func(event *models.ScanEvent) error {
+		logged = append(logged, *event)
+		return nil
+	})
+	defer services.UseRefusedScanLog(nil)
+
 	tests := []struct {
 		name           string
 		requestBody    models.ValidateQRRequest
 		expectedStatus int
 		expectedError  string
+		expectedReason string
 	}{
 		{
-			name: "Valid booking code",
+			name: "No studio",
 			requestBody: models.ValidateQRRequest{
-				BookingCode: "VALID123",
+				Ticket: elsewhere,
 			},
-			expectedStatus: http.StatusInternalServerError, // No DB connection
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "studioId is required",
 		},
 		{
-			name: "Empty booking code",
+			name: "Bare booking code",
 			requestBody: models.ValidateQRRequest{
-				BookingCode: "",
+				Ticket:   "VALID123",
+				StudioID: 1,
 			},
-			expectedStatus: http.StatusInternalServerError, // No DB connection
+			expectedStatus: http.StatusBadRequest, // Rejected before any DB lookup
+			expectedError:  "ticket is not a valid cinema ticket",
+			expectedReason: "malformed_ticket",
+		},
+		{
+			name: "Empty ticket",
+			requestBody: models.ValidateQRRequest{
+				Ticket:   "",
+				StudioID: 1,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "ticket is not a valid cinema ticket",
+			expectedReason: "malformed_ticket",
+		},
+		{
+			name: "Ticket from an unknown key",
+			requestBody: models.ValidateQRRequest{
+				Ticket:   "T1.other.AQIDBA.c2ln",
+				StudioID: 1,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "ticket was signed with an unknown key",
+			expectedReason: "unknown_key",
+		},
+		{
+			name: "Ticket for another studio",
+			requestBody: models.ValidateQRRequest{
+				Ticket:   elsewhere,
+				StudioID: 1,
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  "ticket is for studio 5",
+			expectedReason: "wrong_studio",
 		},
 	}
 
@@ -174,6 +221,16 @@ func TestValidateQRCodeHandler(t *testing.T) {
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+			if tt.expectedReason != "" {
+				assert.Equal(t, tt.expectedReason, response["reason"])
+				if assert.NotEmpty(t, logged) {
+					assert.Equal(t, tt.expectedReason, logged[len(logged)-1].Reason)
+				}
+			}
 		})
 	}
 }
@@ -227,11 +284,21 @@ func TestInvalidJSONBookingRequest(t *testing.T) {
 			endpoint: "/booking/offline",
 			handler:  CreateOfflineBooking,
 		},
+		{
+			name:     "Invalid JSON for price quote",
+			endpoint: "/quote",
+			handler:  QuoteTickets,
+		},
 		{
 			name:     "Invalid JSON for validate QR",
 			endpoint: "/validate",
 			handler:  ValidateQRCode,
 		},
+		{
+			name:     "Invalid JSON for exit scan",
+			endpoint: "/exit",
+			handler:  ExitScan,
+		},
 	}
 
 	for _, tt := range tests {
@@ -263,4 +330,253 @@ func TestInvalidJSONBookingRequest(t *testing.T) {
 			assert.Equal(t, "Invalid request", response["error"])
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestQuoteTicketsHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		requestBody   models.PriceQuoteRequest
+		expectedError string
+	}{
+		{
+			name:          "No seats",
+			requestBody:   models.PriceQuoteRequest{StudioID: 1, ShowtimeID: 1},
+			expectedError: "seatIds must contain at least one seat",
+		},
+		{
+			name: "Unknown customer category",
+			requestBody: models.PriceQuoteRequest{
+				StudioID: 1, ShowtimeID: 1, SeatIDs: []uint{1},
+				Tickets: []models.TicketRequest{{SeatID: 1, Category: "pensioner"}},
+			},
+			expectedError: "category must be one of adult, child, senior or student",
+		},
+		{
+			name: "Ticket for a seat not requested",
+			requestBody: models.PriceQuoteRequest{
+				StudioID: 1, ShowtimeID: 1, SeatIDs: []uint{1},
+				Tickets: []models.TicketRequest{{SeatID: 2, Category: "child"}},
+			},
+			expectedError: "tickets may only name seats listed in seatIds",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/quote", QuoteTickets)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/quote", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
+
+func TestCancelBookingHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		expectedError string
+	}{
+		{
+			name:          "Invalid booking id",
+			path:          "/bookings/abc/cancel",
+			expectedError: "Invalid id",
+		},
+		{
+			name:          "Invalid JSON",
+			path:          "/bookings/1/cancel",
+			body:          "invalid-json",
+			expectedError: "Invalid request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("user", models.User{ID: 1, Email: "test@example.com", Name: "Test User", Role: "customer"})
+				c.Next()
+			})
+			router.POST("/bookings/:id/cancel", CancelBooking)
+
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
+
+func TestCancelSeatsHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", models.User{ID: 1, Email: "test@example.com", Name: "Test User", Role: "customer"})
+		c.Next()
+	})
+	router.POST("/bookings/:id/seats/cancel", CancelSeats)
+
+	for path, expectedError := range map[string]string{
+		"/bookings/abc/seats/cancel": "Invalid id",
+		"/bookings/1/seats/cancel":   "Invalid request",
+	} {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString("invalid-json"))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, expectedError, response["error"])
+	}
+}
+
+func TestExchangeSeatsHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", models.User{ID: 1, Email: "test@example.com", Name: "Test User", Role: "customer"})
+		c.Next()
+	})
+	router.POST("/bookings/:id/seats/exchange", ExchangeSeats)
+
+	for path, expectedError := range map[string]string{
+		"/bookings/abc/seats/exchange": "Invalid id",
+		"/bookings/1/seats/exchange":   "Invalid request",
+	} {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString("invalid-json"))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, expectedError, response["error"])
+	}
+}
+
+func TestBookingStatusHandlersRejectInvalidIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", models.User{ID: 1, Email: "staff@example.com", Name: "Staff", Role: "cashier"})
+		c.Next()
+	})
+	router.GET("/bookings/:id/history", GetBookingHistory)
+	router.POST("/bookings/:id/no-show", MarkNoShow)
+	router.GET("/bookings/:id/scans", GetBookingScans)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/bookings/abc/history", nil),
+		httptest.NewRequest("POST", "/bookings/abc/no-show", nil),
+		httptest.NewRequest("GET", "/bookings/abc/scans", nil),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "Invalid id", response["error"])
+	}
+}
+
+func TestExitScanHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	elsewhere := utils.SignTicket(utils.Ticket{BookingCode: "BOOK123", StudioID: 5, ShowtimeID: 1, SeatIDs: []uint{1}})
+
+	var logged []models.ScanEvent
+	services.UseRefusedScanLog(func(event *models.ScanEvent) error {
+		logged = append(logged, *event)
+		return nil
+	})
+	defer services.UseRefusedScanLog(nil)
+
+	tests := []struct {
+		name           string
+		requestBody    models.ValidateQRRequest
+		expectedStatus int
+		expectedReason string
+	}{
+		{"No studio", models.ValidateQRRequest{Ticket: elsewhere}, http.StatusBadRequest, ""},
+		{"Bare booking code", models.ValidateQRRequest{Ticket: "VALID123", StudioID: 1}, http.StatusBadRequest, "malformed_ticket"},
+		{"Ticket for another studio", models.ValidateQRRequest{Ticket: elsewhere, StudioID: 1, SeatIDs: []uint{1}}, http.StatusConflict, "wrong_studio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/exit", ExitScan)
+
+			jsonData, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/exit", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if tt.expectedReason != "" {
+				assert.Equal(t, tt.expectedReason, response["reason"])
+				if assert.NotEmpty(t, logged) {
+					assert.Equal(t, tt.expectedReason, logged[len(logged)-1].Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestAdmittedCount(t *testing.T) {
+	at := time.Date(2024, 5, 1, 18, 50, 0, 0, time.UTC)
+
+	booking := &models.Booking{
+		Status:  models.BookingUsed,
+		SeatIDs: []int64{1, 2, 3},
+		Items: []models.BookingItem{
+			{SeatID: 1, AdmittedAt: &at},
+			{SeatID: 2, AdmittedAt: &at, ExitedAt: &at},
+			{SeatID: 3},
+		},
+	}
+	assert.Equal(t, 2, admittedCount(booking))
+
+	legacy := &models.Booking{Status: models.BookingUsed, SeatIDs: []int64{1, 2}}
+	assert.Equal(t, 2, admittedCount(legacy))
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"booking-service/payments"
+	"booking-service/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentWebhook receives payment notifications from the payment provider.
+// Anything but a 2xx answer makes the provider deliver the webhook again.
+func PaymentWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := services.HandlePaymentWebhook(c.Request.Header, body); err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// SimulatePayment completes a fake payment. It is only routed, for admins,
+// when the fake payment provider was chosen.
+func SimulatePayment(c *gin.Context) {
+	var req struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := services.SimulatePayment(c.Param("paymentId"), req.Succeeded); err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+func respondPaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payments.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentsUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process payment"})
+	}
+}
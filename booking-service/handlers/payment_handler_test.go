@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"booking-service/payments"
+	"booking-service/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentWebhookHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	provider := payments.NewFakeProvider("secret")
+	_, signedBody := provider.Webhook("fake_1", true)
+
+	tests := []struct {
+		name           string
+		provider       payments.Provider
+		signature      string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "No payment provider",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  "payments are not available",
+		},
+		{
+			name:           "Missing signature",
+			provider:       provider,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "webhook signature is invalid",
+		},
+		{
+			name:           "Wrong signature",
+			provider:       provider,
+			signature:      "00ff",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "webhook signature is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services.UsePaymentProvider(tt.provider)
+			defer services.UsePaymentProvider(nil)
+
+			router := gin.New()
+			router.POST("/payments/webhook", PaymentWebhook)
+
+			req, _ := http.NewRequest("POST", "/payments/webhook", bytes.NewBuffer(signedBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.signature != "" {
+				req.Header.Set(payments.FakeSignatureHeader, tt.signature)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"booking-service/models"
+	"booking-service/services"
+	"github.com/gin-gonic/gin"
+)
+
+func CreatePromoCode(c *gin.Context) {
+	var req models.PromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	promo, err := services.CreatePromoCode(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPromoCodeRequired),
+			errors.Is(err, services.ErrInvalidPromoKind),
+			errors.Is(err, services.ErrInvalidPromoValue),
+			errors.Is(err, services.ErrInvalidPromoBundle),
+			errors.Is(err, services.ErrInvalidPromoWindow),
+			errors.Is(err, services.ErrInvalidPromoCap):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPromoCodeTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promo code"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, promo)
+}
+
+func GetPromoCodes(c *gin.Context) {
+	promos, err := services.GetPromoCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promo codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promos)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"booking-service/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePromoCodeHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		requestBody   models.PromoCodeRequest
+		expectedError string
+	}{
+		{
+			name:          "Missing code",
+			requestBody:   models.PromoCodeRequest{Kind: models.PromoPercent, Value: 10},
+			expectedError: "code is required",
+		},
+		{
+			name:          "Unknown kind",
+			requestBody:   models.PromoCodeRequest{Code: "SPRING", Kind: "half"},
+			expectedError: "kind must be one of percent, fixed or buy_n_get_m",
+		},
+		{
+			name:          "Percent out of range",
+			requestBody:   models.PromoCodeRequest{Code: "SPRING", Kind: models.PromoPercent, Value: 150},
+			expectedError: "percent codes need a value between 1 and 100 and fixed codes a positive value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/admin/promos", CreatePromoCode)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/admin/promos", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
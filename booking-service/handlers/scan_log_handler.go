@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"booking-service/models"
+	"booking-service/services"
+	"github.com/gin-gonic/gin"
+)
+
+// parseUintQuery reads an optional numeric query parameter; absent means 0.
+func parseUintQuery(c *gin.Context, name string) (uint, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseTimeQuery reads an optional RFC 3339 query parameter.
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	at, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	return &at, true
+}
+
+// parseScanQuery reads the scan log filters from the query string.
+func parseScanQuery(c *gin.Context) (models.ScanQuery, bool) {
+	query := models.ScanQuery{
+		BookingCode: c.Query("bookingCode"),
+		DeviceID:    c.Query("deviceId"),
+		Source:      c.Query("source"),
+		Direction:   c.Query("direction"),
+		Result:      c.Query("result"),
+		Reason:      c.Query("reason"),
+	}
+
+	var ok bool
+	for name, field := range map[string]*uint{
+		"showtimeId": &query.ShowtimeID,
+		"studioId":   &query.StudioID,
+		"staffId":    &query.StaffID,
+	} {
+		if *field, ok = parseUintQuery(c, name); !ok {
+			return query, false
+		}
+	}
+	if query.From, ok = parseTimeQuery(c, "from"); !ok {
+		return query, false
+	}
+	if query.To, ok = parseTimeQuery(c, "to"); !ok {
+		return query, false
+	}
+
+	for name, field := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		n, found := parseUintQuery(c, name)
+		if !found {
+			return query, false
+		}
+		*field = int(n)
+	}
+	return query, true
+}
+
+// GetScanLog lists the scans matching the query string, newest first.
+func GetScanLog(c *gin.Context) {
+	query, ok := parseScanQuery(c)
+	if !ok {
+		return
+	}
+
+	log, err := services.ListScans(query)
+	if err != nil {
+		respondScanLogError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, log)
+}
+
+// ExportScanLog downloads the scans matching the query string as CSV.
+func ExportScanLog(c *gin.Context) {
+	query, ok := parseScanQuery(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="scans-%s.csv"`, time.Now().UTC().Format("20060102-150405")))
+	c.Status(http.StatusOK)
+	if err := services.ExportScans(query, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "")
+			c.Header("Content-Disposition", "")
+			respondScanLogError(c, err)
+			return
+		}
+		// Rows were sent already; cut the download short instead.
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// GetScanStats sums up the scans of ?showtimeId= or of ?from= to ?to=, in
+// throughput buckets of ?interval= minutes (default 5).
+func GetScanStats(c *gin.Context) {
+	query, ok := parseScanQuery(c)
+	if !ok {
+		return
+	}
+	interval := 5
+	if v := c.Query("interval"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval"})
+			return
+		}
+		interval = n
+	}
+
+	stats, err := services.GetScanStats(query, time.Duration(interval)*time.Minute)
+	if err != nil {
+		respondScanLogError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func respondScanLogError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrScanLimit),
+		errors.Is(err, services.ErrScanRange),
+		errors.Is(err, services.ErrStatsScope),
+		errors.Is(err, services.ErrScanInterval),
+		errors.Is(err, services.ErrStatsTooMany),
+		errors.Is(err, services.ErrStatsSpan):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
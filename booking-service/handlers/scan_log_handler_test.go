@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanLogHandlersValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/scans", GetScanLog)
+	router.GET("/scans/export", ExportScanLog)
+	router.GET("/scans/stats", GetScanStats)
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedError string
+	}{
+		{"log with bad showtime", "/scans?showtimeId=abc", "Invalid showtimeId"},
+		{"log with bad staff", "/scans?staffId=-3", "Invalid staffId"},
+		{"log with bad time", "/scans?from=yesterday", "Invalid from"},
+		{"log with bad limit", "/scans?limit=ten", "Invalid limit"},
+		{"log with too large a limit", "/scans?limit=5000", "limit must be between 1 and 1000"},
+		{"log with backwards range", "/scans?from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z", "from must be before to"},
+		{"export with bad time", "/scans/export?to=later", "Invalid to"},
+		{"export with backwards range", "/scans/export?from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z", "from must be before to"},
+		{"stats without scope", "/scans/stats?studioId=2", "give a showtimeId or both from and to"},
+		{"stats with bad interval", "/scans/stats?showtimeId=4&interval=often", "Invalid interval"},
+		{"stats with too long an interval", "/scans/stats?showtimeId=4&interval=90", "interval must be between 1 and 60 minutes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"booking-service/models"
+	"booking-service/services"
+	"booking-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetScannerBundle serves the bundle scanners validate tickets with while
+// offline, for ?showtimeId= or for ?date= and optionally ?studioId=.
+func GetScannerBundle(c *gin.Context) {
+	var ids [2]uint64
+	for i, name := range []string{"showtimeId", "studioId"} {
+		if v := c.Query(name); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return
+			}
+			ids[i] = id
+		}
+	}
+
+	bundle, err := services.GetScannerBundle(uint(ids[0]), c.Query("date"), uint(ids[1]))
+	if err != nil {
+		respondScannerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// UploadScans takes the scans a scanner made offline and answers how each
+// was settled.
+func UploadScans(c *gin.Context) {
+	var req models.ScanUpload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, _ := c.Get("user")
+	userObj := user.(models.User)
+
+	result, err := services.UploadScans(req, userObj)
+	if err != nil {
+		respondScannerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func respondScannerError(c *gin.Context, err error) {
+	var invalid *utils.SeatRequestError
+	switch {
+	case errors.Is(err, services.ErrBundleScope),
+		errors.Is(err, services.ErrDeviceRequired),
+		errors.Is(err, services.ErrStudioRequired),
+		errors.Is(err, services.ErrNoScans),
+		errors.Is(err, services.ErrTooManyScans),
+		errors.Is(err, services.ErrScanDirection),
+		errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"booking-service/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScannerHandlersValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", models.User{ID: 1, Email: "staff@example.com", Name: "Staff", Role: "cashier"})
+		c.Next()
+	})
+	router.GET("/scanner/bundle", GetScannerBundle)
+	router.POST("/scanner/scans", UploadScans)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		expectedError string
+	}{
+		{"bundle without scope", "GET", "/scanner/bundle", "", "give a showtimeId or a date"},
+		{"bundle with bad showtime", "GET", "/scanner/bundle?showtimeId=abc", "", "Invalid showtimeId"},
+		{"bundle with bad studio", "GET", "/scanner/bundle?date=2024-05-01&studioId=-1", "", "Invalid studioId"},
+		{"upload with invalid json", "POST", "/scanner/scans", "invalid-json", "Invalid request"},
+		{"upload without device", "POST", "/scanner/scans", `{"scans":[{"ticket":"T1.k1.AA.AA"}]}`, "deviceId is required"},
+		{"upload without studio", "POST", "/scanner/scans", `{"deviceId":"door-1","scans":[{"ticket":"T1.k1.AA.AA"}]}`, "studioId is required"},
+		{"upload without scans", "POST", "/scanner/scans", `{"deviceId":"door-1","studioId":1,"scans":[]}`, "scans must contain at least one scan"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
@@ -3,9 +3,14 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"booking-service/database"
 	"booking-service/handlers"
+	"booking-service/middleware"
+	"booking-service/payments"
+	"booking-service/services"
+	"booking-service/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,6 +18,19 @@ import (
 
 func main() {
 	database.Init()
+
+	provider, err := payments.FromEnv()
+	if err != nil {
+		log.Fatal("Failed to set up payments:", err)
+	}
+	services.UsePaymentProvider(provider)
+
+	ticketKeys, err := utils.TicketKeysFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load ticket signing keys:", err)
+	}
+	utils.UseTicketKeys(ticketKeys)
+	services.StartPaymentExpirySweeper(time.Minute)
 	
 	r := gin.Default()
 	
@@ -20,10 +38,37 @@ func main() {
 	{
 		booking.POST("/online", handlers.AuthMiddleware(), handlers.CreateOnlineBooking)
 		booking.POST("/offline", handlers.CreateOfflineBooking)
-		booking.POST("/validate", handlers.ValidateQRCode)
+		booking.POST("/quote", handlers.QuoteTickets)
+		booking.POST("/validate", handlers.AuthMiddleware(), middleware.RequireRole("admin", "cashier"), handlers.ValidateQRCode)
+		booking.POST("/exit", handlers.AuthMiddleware(), middleware.RequireRole("admin", "cashier"), handlers.ExitScan)
 		booking.GET("/my-bookings", handlers.AuthMiddleware(), handlers.GetUserBookings)
+		booking.POST("/bookings/:id/cancel", handlers.AuthMiddleware(), handlers.CancelBooking)
+		booking.POST("/bookings/:id/seats/cancel", handlers.AuthMiddleware(), handlers.CancelSeats)
+		booking.POST("/bookings/:id/seats/exchange", handlers.AuthMiddleware(), handlers.ExchangeSeats)
+		booking.GET("/bookings/:id/history", handlers.AuthMiddleware(), handlers.GetBookingHistory)
+		booking.POST("/bookings/:id/no-show", handlers.AuthMiddleware(), middleware.RequireRole("admin", "cashier"), handlers.MarkNoShow)
+		booking.GET("/bookings/:id/scans", handlers.AuthMiddleware(), middleware.RequireRole("admin", "cashier"), handlers.GetBookingScans)
+		booking.POST("/payments/webhook", handlers.PaymentWebhook)
+		if provider.Name() == payments.FakeProviderName {
+			booking.POST("/payments/fake/:paymentId", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handlers.SimulatePayment)
+		}
 	}
 	
+	admin := r.Group("/api/booking/admin", middleware.AuthMiddleware(), middleware.RequireRole("admin"))
+	{
+		admin.POST("/promos", handlers.CreatePromoCode)
+		admin.GET("/promos", handlers.GetPromoCodes)
+		admin.GET("/scans", handlers.GetScanLog)
+		admin.GET("/scans/export", handlers.ExportScanLog)
+		admin.GET("/scans/stats", handlers.GetScanStats)
+	}
+
+	scanner := r.Group("/api/booking/scanner", middleware.AuthMiddleware(), middleware.RequireRole("admin", "cashier"))
+	{
+		scanner.GET("/bundle", handlers.GetScannerBundle)
+		scanner.POST("/scans", handlers.UploadScans)
+	}
+
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "OK", "service": "booking-service"})
 	})
@@ -26,11 +26,11 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		token := strings.Replace(authHeader, "Bearer ", "", 1)
-		
+
 		// Verify token with auth service
 		reqBody := map[string]string{"token": token}
 		jsonData, _ := json.Marshal(reqBody)
-		
+
 		resp, err := http.Post(authServiceURL+"/api/auth/verify", "application/json", bytes.NewBuffer(jsonData))
 		if err != nil || resp.StatusCode != 200 {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -54,4 +54,28 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("user", result.User)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RequireRole lets the request through only when AuthMiddleware stored a
+// user with one of the given roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := c.Get("user")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "No token provided"})
+			c.Abort()
+			return
+		}
+
+		userObj := user.(models.User)
+		for _, role := range roles {
+			if userObj.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
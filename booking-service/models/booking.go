@@ -6,32 +6,158 @@ import (
 	"github.com/lib/pq"
 )
 
+// Refund policies a cancellation can fall under.
+const (
+	RefundFull    = "full"
+	RefundPartial = "partial"
+	RefundNone    = "none"
+)
+
+// Payment statuses.
+const (
+	PaymentAuthorized = "authorized"
+	PaymentCapturing  = "capturing"
+	PaymentCaptured   = "captured"
+	PaymentFailed     = "failed"
+	PaymentRefunded   = "refunded"
+	PaymentVoided     = "voided"
+)
+
 type Booking struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	BookingCode string         `json:"booking_code" gorm:"uniqueIndex;not null"`
-	UserID      *uint          `json:"user_id"`
-	UserName    string         `json:"user_name" gorm:"not null"`
-	UserEmail   string         `json:"user_email" gorm:"not null"`
-	StudioID    uint           `json:"studio_id" gorm:"not null"`
-	SeatIDs     pq.Int64Array  `json:"seat_ids" gorm:"type:integer[]"`
-	QRCode      string         `json:"qr_code" gorm:"type:text"`
-	BookingType string         `json:"booking_type" gorm:"default:online"`
-	Status      string         `json:"status" gorm:"default:active"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	BookingCode     string         `json:"booking_code" gorm:"uniqueIndex;not null"`
+	UserID          *uint          `json:"user_id"`
+	UserName        string         `json:"user_name" gorm:"not null"`
+	UserEmail       string         `json:"user_email" gorm:"not null"`
+	StudioID        uint           `json:"studio_id" gorm:"not null"`
+	ShowtimeID      uint           `json:"showtime_id" gorm:"index"`
+	SeatIDs         pq.Int64Array  `json:"seat_ids" gorm:"type:integer[]"`
+	HoldToken       string         `json:"-"`
+	QRCode          string         `json:"qr_code" gorm:"type:text"`
+	BookingType     string         `json:"booking_type" gorm:"default:online"`
+	Status          BookingStatus  `json:"status" gorm:"default:active"`
+	PromoCode       string         `json:"promo_code,omitempty"`
+	Discount        int64          `json:"discount"`
+	TotalAmount     int64          `json:"total_amount"`
+	Currency        string         `json:"currency"`
+	Items           []BookingItem  `json:"items,omitempty" gorm:"foreignKey:BookingID"`
+	Payments        []Payment      `json:"payments,omitempty" gorm:"foreignKey:BookingID"`
+	CancelledAt     *time.Time     `json:"cancelled_at,omitempty"`
+	CancelledBy     *uint          `json:"cancelled_by,omitempty"`
+	CancelledByRole string         `json:"cancelled_by_role,omitempty"`
+	CancelReason    string         `json:"cancel_reason,omitempty"`
+	RefundPolicy    string         `json:"refund_policy,omitempty"`
+	RefundAmount    int64          `json:"refund_amount"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// Customer categories a ticket can be priced for.
+const (
+	CustomerAdult   = "adult"
+	CustomerChild   = "child"
+	CustomerSenior  = "senior"
+	CustomerStudent = "student"
+)
+
+// Showtime slots that set the base ticket price.
+const (
+	SlotMatinee = "matinee"
+	SlotEvening = "evening"
+	SlotWeekend = "weekend"
+)
+
+// BookingItem is the priced ticket for one seat of a booking. Amounts are in
+// the minor unit of the booking's currency. Items of seats dropped from the
+// booking are kept with the time they were cancelled. AdmittedAt is when
+// the seat first got in; a seat scanned out has ExitedAt set until it
+// enters again.
+type BookingItem struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	BookingID        uint       `json:"booking_id" gorm:"not null;index"`
+	SeatID           uint       `json:"seat_id" gorm:"not null"`
+	SeatNumber       string     `json:"seat_number"`
+	SeatType         string     `json:"seat_type"`
+	CustomerCategory string     `json:"customer_category"`
+	Slot             string     `json:"slot"`
+	Format           string     `json:"format"`
+	BasePrice        int64      `json:"base_price"`
+	FormatSurcharge  int64      `json:"format_surcharge"`
+	SeatSurcharge    int64      `json:"seat_surcharge"`
+	Discount         int64      `json:"discount"`
+	Price            int64      `json:"price"`
+	CancelledAt      *time.Time `json:"cancelled_at,omitempty"`
+	AdmittedAt       *time.Time `json:"admitted_at,omitempty"`
+	LastEntryAt      *time.Time `json:"last_entry_at,omitempty"`
+	ExitedAt         *time.Time `json:"exited_at,omitempty"`
+	Reentries        int        `json:"reentries"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Inside tells whether the seat's holder is in the auditorium.
+func (item BookingItem) Inside() bool {
+	return item.AdmittedAt != nil && item.ExitedAt == nil
+}
+
+// Payment is money taken for a booking through a payment provider.
+// ClientSecret lets the customer's client complete the payment with the
+// provider. PendingRefund is owed back to the customer but not yet
+// confirmed by the provider.
+type Payment struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	BookingID      uint      `json:"booking_id" gorm:"not null;index"`
+	Provider       string    `json:"provider" gorm:"not null"`
+	PaymentID      string    `json:"payment_id" gorm:"uniqueIndex;not null"`
+	ClientSecret   string    `json:"client_secret,omitempty"`
+	Amount         int64     `json:"amount"`
+	Currency       string    `json:"currency"`
+	Status         string    `json:"status" gorm:"not null"`
+	RefundedAmount int64     `json:"refunded_amount"`
+	PendingRefund  int64     `json:"pending_refund"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TicketRequest names the customer category of one booked seat. Seats
+// without a ticket entry are priced for adults.
+type TicketRequest struct {
+	SeatID   uint   `json:"seatId"`
+	Category string `json:"category"`
 }
 
 type OnlineBookingRequest struct {
-	StudioID uint   `json:"studioId"`
-	SeatIDs  []uint `json:"seatIds"`
+	StudioID   uint            `json:"studioId"`
+	ShowtimeID uint            `json:"showtimeId"`
+	SeatIDs    []uint          `json:"seatIds"`
+	Tickets    []TicketRequest `json:"tickets"`
+	PromoCode  string          `json:"promoCode"`
 }
 
 type OfflineBookingRequest struct {
-	StudioID      uint   `json:"studioId"`
-	SeatIDs       []uint `json:"seatIds"`
-	CustomerName  string `json:"customerName"`
-	CustomerEmail string `json:"customerEmail"`
+	StudioID      uint            `json:"studioId"`
+	ShowtimeID    uint            `json:"showtimeId"`
+	SeatIDs       []uint          `json:"seatIds"`
+	Tickets       []TicketRequest `json:"tickets"`
+	PromoCode     string          `json:"promoCode"`
+	CustomerName  string          `json:"customerName"`
+	CustomerEmail string          `json:"customerEmail"`
+}
+
+// PriceQuoteRequest asks what seats would cost without booking them.
+type PriceQuoteRequest struct {
+	StudioID   uint            `json:"studioId"`
+	ShowtimeID uint            `json:"showtimeId"`
+	SeatIDs    []uint          `json:"seatIds"`
+	Tickets    []TicketRequest `json:"tickets"`
+}
+
+// PriceQuote is the line-item price of a set of seats.
+type PriceQuote struct {
+	ShowtimeID  uint          `json:"showtimeId"`
+	Items       []BookingItem `json:"items"`
+	TotalAmount int64         `json:"totalAmount"`
+	Currency    string        `json:"currency"`
 }
 
 type User struct {
@@ -41,6 +167,30 @@ type User struct {
 	Role  string `json:"role"`
 }
 
+type CancelBookingRequest struct {
+	Reason string `json:"reason"`
+}
+
+type CancelSeatsRequest struct {
+	SeatIDs []uint `json:"seatIds"`
+	Reason  string `json:"reason"`
+}
+
+// ExchangeSeatsRequest moves a booking from SeatIDs to NewSeatIDs, pairing
+// the seats in order.
+type ExchangeSeatsRequest struct {
+	SeatIDs    []uint `json:"seatIds"`
+	NewSeatIDs []uint `json:"newSeatIds"`
+}
+
+// ValidateQRRequest carries the text of a scanned QR code, a signed ticket,
+// the studio the scanner stands at and optionally the scanner's ID and the
+// seats passing. Without seats every seat of the booking that may pass
+// does. Live scans happen when they reach booking service; only offline
+// uploads say when they were made.
 type ValidateQRRequest struct {
-	BookingCode string `json:"bookingCode"`
-}
\ No newline at end of file
+	Ticket   string `json:"ticket"`
+	StudioID uint   `json:"studioId"`
+	DeviceID string `json:"deviceId"`
+	SeatIDs  []uint `json:"seatIds"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// BookingStatus is where a booking is in its life. Bookings only move
+// between statuses along bookingTransitions.
+type BookingStatus string
+
+// Booking statuses. Online bookings wait in pending_payment until the
+// payment provider reports the payment, become paid once it is captured
+// and active once cinema service has sold the held seats. Unpaid bookings
+// expire with their seat hold; paid bookings whose seats could not be sold
+// are refunded.
+const (
+	BookingPendingPayment BookingStatus = "pending_payment"
+	BookingPaid           BookingStatus = "paid"
+	BookingActive         BookingStatus = "active"
+	BookingUsed           BookingStatus = "used"
+	BookingPaymentFailed  BookingStatus = "payment_failed"
+	BookingCancelled      BookingStatus = "cancelled"
+	BookingExpired        BookingStatus = "expired"
+	BookingRefunded       BookingStatus = "refunded"
+	BookingNoShow         BookingStatus = "no_show"
+)
+
+// bookingTransitions lists the statuses each status may move to. Statuses
+// that are not listed are final.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingPendingPayment: {BookingPaid, BookingPaymentFailed, BookingCancelled, BookingExpired},
+	BookingPaid:           {BookingActive, BookingRefunded},
+	BookingActive:         {BookingUsed, BookingCancelled, BookingNoShow},
+}
+
+// CanBecome tells whether a booking in status s may move to next.
+func (s BookingStatus) CanBecome(next BookingStatus) bool {
+	for _, allowed := range bookingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// BookingStatusChange records one move of a booking between statuses. The
+// first change of every booking has an empty FromStatus. Changes made by
+// the service itself, such as payment webhooks and expiry, have no actor.
+type BookingStatusChange struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	BookingID  uint          `json:"booking_id" gorm:"index;not null"`
+	FromStatus BookingStatus `json:"from_status"`
+	ToStatus   BookingStatus `json:"to_status" gorm:"not null"`
+	ActorID    *uint         `json:"actor_id,omitempty"`
+	ActorRole  string        `json:"actor_role,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
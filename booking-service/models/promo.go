@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Kinds of discount a promo code can give.
+const (
+	PromoPercent  = "percent"
+	PromoFixed    = "fixed"
+	PromoBuyNGetM = "buy_n_get_m"
+)
+
+// PromoCode is a discount customers can apply to a booking. Value is the
+// percentage off for percent codes and the amount off, in the minor unit of
+// the currency, for fixed codes. Buy-N-get-M codes make the M cheapest of
+// every N+M tickets free. Zero caps and empty restriction lists mean no
+// limit.
+type PromoCode struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	Code             string         `json:"code" gorm:"uniqueIndex;not null"`
+	Kind             string         `json:"kind" gorm:"not null"`
+	Value            int64          `json:"value"`
+	BuyCount         int            `json:"buy_count"`
+	FreeCount        int            `json:"free_count"`
+	FirstBookingOnly bool           `json:"first_booking_only"`
+	StartsAt         *time.Time     `json:"starts_at"`
+	EndsAt           *time.Time     `json:"ends_at"`
+	MaxUses          int            `json:"max_uses"`
+	MaxUsesPerUser   int            `json:"max_uses_per_user"`
+	UsedCount        int            `json:"used_count" gorm:"not null;default:0"`
+	MovieIDs         pq.Int64Array  `json:"movie_ids" gorm:"type:integer[]"`
+	StudioIDs        pq.Int64Array  `json:"studio_ids" gorm:"type:integer[]"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// PromoRedemption records one use of a promo code by a booking.
+type PromoRedemption struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	PromoCodeID uint      `json:"promo_code_id" gorm:"not null;index"`
+	BookingID   uint      `json:"booking_id" gorm:"not null;uniqueIndex"`
+	UserID      *uint     `json:"user_id" gorm:"index"`
+	UserEmail   string    `json:"user_email" gorm:"index"`
+	Discount    int64     `json:"discount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type PromoCodeRequest struct {
+	Code             string     `json:"code"`
+	Kind             string     `json:"kind"`
+	Value            int64      `json:"value"`
+	BuyCount         int        `json:"buyCount"`
+	FreeCount        int        `json:"freeCount"`
+	FirstBookingOnly bool       `json:"firstBookingOnly"`
+	StartsAt         *time.Time `json:"startsAt"`
+	EndsAt           *time.Time `json:"endsAt"`
+	MaxUses          int        `json:"maxUses"`
+	MaxUsesPerUser   int        `json:"maxUsesPerUser"`
+	MovieIDs         []uint     `json:"movieIds"`
+	StudioIDs        []uint     `json:"studioIds"`
+}
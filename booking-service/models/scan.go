@@ -0,0 +1,223 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Where a scan was checked: by booking service while the scanner was
+// online, or by the scanner itself and uploaded later.
+const (
+	ScanOnline  = "online"
+	ScanOffline = "offline"
+)
+
+// Which way a scan lets people through the entrance.
+const (
+	ScanEntry = "entry"
+	ScanExit  = "exit"
+)
+
+// Outcomes of a scan. A duplicate is a ticket admitted more than once; the
+// first admission is the one booking service heard of first.
+const (
+	ScanAdmitted  = "admitted"
+	ScanExited    = "exited"
+	ScanDuplicate = "duplicate"
+	ScanRejected  = "rejected"
+	ScanInvalid   = "invalid"
+)
+
+// Re-entry policies. Under none every seat gets in once; under exit_scan a
+// seat gets in again after it was scanned out; under free it gets in again
+// without an exit scan.
+const (
+	ReentryNone     = "none"
+	ReentryExitScan = "exit_scan"
+	ReentryFree     = "free"
+)
+
+// Reasons a ticket is refused at the entrance.
+const (
+	RejectMalformedTicket = "malformed_ticket"
+	RejectUnknownKey      = "unknown_key"
+	RejectBadSignature    = "bad_signature"
+	RejectNotFound        = "booking_not_found"
+	RejectWrongStudio     = "wrong_studio"
+	RejectTooEarly        = "too_early"
+	RejectTooLate         = "too_late"
+	RejectAlreadyUsed     = "already_used"
+	RejectCancelled       = "booking_cancelled"
+	RejectNotActive       = "booking_not_active"
+	RejectSeatNotBooked   = "seat_not_in_booking"
+	RejectAlreadyInside   = "already_inside"
+	RejectNotInside       = "not_inside"
+	RejectReentryLimit    = "reentry_limit"
+	RejectReentryClosed   = "reentry_closed"
+)
+
+// ScanEvent records one scan of a ticket at an entrance: where, on which
+// device, by which staff account and with what outcome. SeatIDs are the
+// seats that passed. Reason is the rejection reason of scans that let
+// nobody through. Invalid tickets have no booking.
+type ScanEvent struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	BookingID   *uint         `json:"booking_id,omitempty" gorm:"index"`
+	BookingCode string        `json:"booking_code,omitempty" gorm:"index"`
+	ShowtimeID  uint          `json:"showtime_id,omitempty" gorm:"index"`
+	StudioID    uint          `json:"studio_id,omitempty" gorm:"index"`
+	DeviceID    string        `json:"device_id,omitempty" gorm:"index"`
+	StaffID     *uint         `json:"staff_id,omitempty" gorm:"index"`
+	StaffRole   string        `json:"staff_role,omitempty"`
+	Source      string        `json:"source" gorm:"not null"`
+	Direction   string        `json:"direction" gorm:"not null;default:entry"`
+	SeatIDs     pq.Int64Array `json:"seat_ids" gorm:"type:integer[]"`
+	Result      string        `json:"result" gorm:"not null;index"`
+	Reason      string        `json:"reason,omitempty"`
+	DuplicateOf *uint         `json:"duplicate_of,omitempty"`
+	UploadedBy  *uint         `json:"uploaded_by,omitempty"`
+	ScannedAt   time.Time     `json:"scanned_at" gorm:"index"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// ScannerKey is a public key scanners check ticket signatures with.
+type ScannerKey struct {
+	KeyID     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"`
+}
+
+// ScannerTicket is a booking a scanner may admit, with the seats that
+// already got in and those inside now.
+type ScannerTicket struct {
+	BookingCode     string `json:"bookingCode"`
+	StudioID        uint   `json:"studioId"`
+	ShowtimeID      uint   `json:"showtimeId"`
+	SeatIDs         []uint `json:"seatIds"`
+	AdmittedSeatIDs []uint `json:"admittedSeatIds"`
+	InsideSeatIDs   []uint `json:"insideSeatIds"`
+}
+
+// BookingScans is what staff see of a booking's admissions: each seat with
+// its admission times and every scan of the ticket, oldest first.
+type BookingScans struct {
+	BookingID     uint          `json:"bookingId"`
+	BookingCode   string        `json:"bookingCode"`
+	Status        BookingStatus `json:"status"`
+	SeatCount     int           `json:"seatCount"`
+	AdmittedCount int           `json:"admittedCount"`
+	InsideCount   int           `json:"insideCount"`
+	Seats         []BookingItem `json:"seats"`
+	Scans         []ScanEvent   `json:"scans"`
+}
+
+// ScannerShowtime is a showtime a scanner admits to, when admission is
+// open and until when seats may get in again.
+type ScannerShowtime struct {
+	ID                uint      `json:"id"`
+	StudioID          uint      `json:"studioId"`
+	StartTime         time.Time `json:"startTime"`
+	AdmissionOpensAt  time.Time `json:"admissionOpensAt"`
+	AdmissionClosesAt time.Time `json:"admissionClosesAt"`
+	ReentryClosesAt   time.Time `json:"reentryClosesAt"`
+}
+
+// ScannerBundle is what a scanner downloads to validate tickets without a
+// connection: the keys tickets are signed with, the re-entry rules, the
+// showtimes it covers and the bookings that may still be admitted to them.
+type ScannerBundle struct {
+	GeneratedAt   time.Time         `json:"generatedAt"`
+	ReentryPolicy string            `json:"reentryPolicy"`
+	ReentryLimit  int               `json:"reentryLimit"`
+	Showtimes     []ScannerShowtime `json:"showtimes"`
+	Keys          []ScannerKey      `json:"keys"`
+	Tickets       []ScannerTicket   `json:"tickets"`
+}
+
+// ScanUpload is a batch of scans a scanner made while offline, optionally
+// at the entrance of one studio.
+type ScanUpload struct {
+	DeviceID string        `json:"deviceId"`
+	StudioID uint          `json:"studioId"`
+	Scans    []OfflineScan `json:"scans"`
+}
+
+// OfflineScan is one scan in an upload: the text of the QR code, when it
+// was scanned, which way (entry unless given) and optionally which seats
+// passed.
+type OfflineScan struct {
+	Ticket    string    `json:"ticket"`
+	ScannedAt time.Time `json:"scannedAt"`
+	Direction string    `json:"direction"`
+	SeatIDs   []uint    `json:"seatIds"`
+}
+
+// ScanUploadResult tells a scanner how booking service settled its scans,
+// in the order they were uploaded.
+type ScanUploadResult struct {
+	Admitted   int         `json:"admitted"`
+	Exited     int         `json:"exited"`
+	Duplicates int         `json:"duplicates"`
+	Rejected   int         `json:"rejected"`
+	Scans      []ScanEvent `json:"scans"`
+}
+
+// ScanQuery picks scans out of the scan log. Empty fields match every
+// scan; From and To bound when the scans were made.
+type ScanQuery struct {
+	ShowtimeID  uint
+	StudioID    uint
+	BookingCode string
+	DeviceID    string
+	StaffID     uint
+	Source      string
+	Direction   string
+	Result      string
+	Reason      string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+// ScanLog is one page of the scan log, newest scans first.
+type ScanLog struct {
+	Total  int64       `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+	Scans  []ScanEvent `json:"scans"`
+}
+
+// DeviceScanStats counts the scans made on one scanner.
+type DeviceScanStats struct {
+	DeviceID string `json:"deviceId"`
+	Scans    int    `json:"scans"`
+	Admitted int    `json:"admitted"`
+	Refused  int    `json:"refused"`
+}
+
+// ScanBucket counts the scans made in one interval. Seats is how many
+// seats the admissions let in.
+type ScanBucket struct {
+	Start    time.Time `json:"start"`
+	Admitted int       `json:"admitted"`
+	Seats    int       `json:"seats"`
+	Refused  int       `json:"refused"`
+}
+
+// ScanStats sums up the scans matching a query: outcomes, refusal reasons,
+// scanners and how fast people got in.
+type ScanStats struct {
+	Total            int               `json:"total"`
+	Results          map[string]int    `json:"results"`
+	Reasons          map[string]int    `json:"reasons"`
+	Devices          []DeviceScanStats `json:"devices"`
+	FirstAdmissionAt *time.Time        `json:"firstAdmissionAt"`
+	LastAdmissionAt  *time.Time        `json:"lastAdmissionAt"`
+	AdmittedSeats    int               `json:"admittedSeats"`
+	SeatsPerMinute   float64           `json:"seatsPerMinute"`
+	PeakSeats        int               `json:"peakSeats"`
+	IntervalMinutes  int               `json:"intervalMinutes"`
+	Throughput       []ScanBucket      `json:"throughput"`
+}
@@ -0,0 +1,168 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+	FakeProviderName = "fake"
+	// FakeSignatureHeader carries the HMAC-SHA256 of a fake webhook body.
+	FakeSignatureHeader = "X-Fake-Signature"
+)
+
+type fakePayment struct {
+	authorized int64
+	captured   int64
+	refunded   int64
+	voided     bool
+}
+
+// FakeProvider keeps payments in memory and signs its webhooks with a
+// shared secret. It stands in for a real provider in tests and
+// development.
+type FakeProvider struct {
+	secret []byte
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+}
+
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{secret: []byte(secret), payments: map[string]*fakePayment{}}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) Authorize(req AuthorizeRequest) (*Authorization, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	id := "fake_" + hex.EncodeToString(b)
+
+	p.mu.Lock()
+	p.payments[id] = &fakePayment{authorized: req.Amount}
+	p.mu.Unlock()
+
+	return &Authorization{PaymentID: id, ClientSecret: id + "_secret"}, nil
+}
+
+func (p *FakeProvider) Capture(paymentID string, amount int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[paymentID]
+	if !ok {
+		return ErrUnknownPayment
+	}
+	if payment.voided {
+		return fmt.Errorf("payment was voided")
+	}
+	if amount > payment.authorized {
+		return fmt.Errorf("cannot capture %d of a %d authorization", amount, payment.authorized)
+	}
+	payment.captured = amount
+	return nil
+}
+
+func (p *FakeProvider) Void(paymentID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[paymentID]
+	if !ok {
+		return ErrUnknownPayment
+	}
+	if payment.captured > 0 {
+		return fmt.Errorf("cannot void a captured payment")
+	}
+	payment.voided = true
+	return nil
+}
+
+func (p *FakeProvider) Refund(paymentID string, amount int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[paymentID]
+	if !ok {
+		return ErrUnknownPayment
+	}
+	if payment.refunded+amount > payment.captured {
+		return fmt.Errorf("cannot refund %d of %d captured", amount, payment.captured-payment.refunded)
+	}
+	payment.refunded += amount
+	return nil
+}
+
+// Voided reports whether a payment was voided.
+func (p *FakeProvider) Voided(paymentID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[paymentID]
+	return ok && payment.voided
+}
+
+// Captured and Refunded report how much of a payment was taken and paid
+// back.
+func (p *FakeProvider) Captured(paymentID string) (captured, refunded int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if payment, ok := p.payments[paymentID]; ok {
+		return payment.captured, payment.refunded
+	}
+	return 0, 0
+}
+
+func (p *FakeProvider) sign(body []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p *FakeProvider) VerifyWebhook(header http.Header, body []byte) (*Event, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil {
+		return nil, ErrInvalidWebhook
+	}
+	expected, _ := hex.DecodeString(p.sign(body))
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrInvalidWebhook
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil || event.PaymentID == "" {
+		return nil, ErrInvalidWebhook
+	}
+	return &event, nil
+}
+
+// Webhook builds the signed webhook the fake provider would send when a
+// payment succeeds or fails.
+func (p *FakeProvider) Webhook(paymentID string, succeeded bool) (http.Header, []byte) {
+	event := Event{Type: EventPaymentFailed, PaymentID: paymentID}
+	if succeeded {
+		event.Type = EventPaymentSucceeded
+	}
+	body, _ := json.Marshal(event)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, p.sign(body))
+	return header, body
+}
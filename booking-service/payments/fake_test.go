@@ -0,0 +1,114 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeProviderPayments(t *testing.T) {
+	provider := NewFakeProvider("secret")
+
+	_, err := provider.Authorize(AuthorizeRequest{BookingCode: "b1", Amount: 0, Currency: "USD"})
+	assert.Error(t, err)
+
+	auth, err := provider.Authorize(AuthorizeRequest{BookingCode: "b1", Amount: 2400, Currency: "USD"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEmpty(t, auth.PaymentID)
+	assert.NotEmpty(t, auth.ClientSecret)
+
+	assert.Error(t, provider.Capture(auth.PaymentID, 2500))
+	assert.NoError(t, provider.Capture(auth.PaymentID, 2400))
+	assert.ErrorIs(t, provider.Capture("fake_missing", 100), ErrUnknownPayment)
+
+	assert.NoError(t, provider.Refund(auth.PaymentID, 1000))
+	assert.Error(t, provider.Refund(auth.PaymentID, 1500))
+
+	captured, refunded := provider.Captured(auth.PaymentID)
+	assert.Equal(t, int64(2400), captured)
+	assert.Equal(t, int64(1000), refunded)
+}
+
+func TestFakeProviderVoid(t *testing.T) {
+	provider := NewFakeProvider("secret")
+
+	auth, err := provider.Authorize(AuthorizeRequest{BookingCode: "b1", Amount: 2400, Currency: "USD"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, provider.Void(auth.PaymentID))
+	assert.NoError(t, provider.Void(auth.PaymentID))
+	assert.True(t, provider.Voided(auth.PaymentID))
+	assert.Error(t, provider.Capture(auth.PaymentID, 2400))
+	assert.ErrorIs(t, provider.Void("fake_missing"), ErrUnknownPayment)
+
+	captured, err := provider.Authorize(AuthorizeRequest{BookingCode: "b2", Amount: 2400, Currency: "USD"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, provider.Capture(captured.PaymentID, 2400))
+	assert.Error(t, provider.Void(captured.PaymentID))
+	assert.False(t, provider.Voided(captured.PaymentID))
+}
+
+func TestFakeProviderWebhooks(t *testing.T) {
+	provider := NewFakeProvider("secret")
+
+	header, body := provider.Webhook("fake_1", true)
+	event, err := provider.VerifyWebhook(header, body)
+	if assert.NoError(t, err) {
+		assert.Equal(t, Event{Type: EventPaymentSucceeded, PaymentID: "fake_1"}, *event)
+	}
+
+	header, body = provider.Webhook("fake_1", false)
+	event, err = provider.VerifyWebhook(header, body)
+	if assert.NoError(t, err) {
+		assert.Equal(t, EventPaymentFailed, event.Type)
+	}
+
+	// A webhook signed with another secret or altered on the way is
+	// rejected.
+	header, body = NewFakeProvider("other").Webhook("fake_1", true)
+	_, err = provider.VerifyWebhook(header, body)
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+	header, _ = provider.Webhook("fake_1", true)
+	_, err = provider.VerifyWebhook(header, []byte(`{"type":"payment.succeeded","paymentId":"fake_2"}`))
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+	header.Del(FakeSignatureHeader)
+	_, err = provider.VerifyWebhook(header, body)
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		secret   string
+		want     error
+	}{
+		{"no provider", "", "secret", ErrNoProvider},
+		{"fake without a secret", FakeProviderName, "", ErrNoWebhookSecret},
+		{"fake with a secret", FakeProviderName, "secret", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PAYMENT_PROVIDER", tt.provider)
+			t.Setenv("PAYMENT_WEBHOOK_SECRET", tt.secret)
+
+			provider, err := FromEnv()
+			assert.ErrorIs(t, err, tt.want)
+			if tt.want == nil {
+				assert.Equal(t, FakeProviderName, provider.Name())
+			}
+		})
+	}
+
+	t.Setenv("PAYMENT_PROVIDER", "stripe")
+	_, err := FromEnv()
+	assert.Error(t, err)
+}
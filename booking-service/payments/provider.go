@@ -0,0 +1,82 @@
+// Package payments connects booking service to payment providers.
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+var (
+	ErrInvalidWebhook  = errors.New("webhook signature is invalid")
+	ErrUnknownPayment  = errors.New("payment not found at the provider")
+	ErrNoProvider      = errors.New("PAYMENT_PROVIDER is not set")
+	ErrNoWebhookSecret = errors.New("PAYMENT_WEBHOOK_SECRET is not set")
+)
+
+// Webhook event types providers report.
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+)
+
+// AuthorizeRequest asks a provider to set money aside for a booking.
+// Amount is in the minor unit of Currency.
+type AuthorizeRequest struct {
+	BookingCode string
+	Amount      int64
+	Currency    string
+}
+
+// Authorization is a payment the customer still has to complete.
+// ClientSecret is handed to the client so it can finish the payment with
+// the provider.
+type Authorization struct {
+	PaymentID    string
+	ClientSecret string
+}
+
+// Event is a verified webhook notification about a payment.
+type Event struct {
+	Type      string `json:"type"`
+	PaymentID string `json:"paymentId"`
+}
+
+// Provider is a payment provider booking service can take payments with.
+type Provider interface {
+	// Name identifies the provider in stored payments.
+	Name() string
+	// Authorize starts a payment for a booking.
+	Authorize(req AuthorizeRequest) (*Authorization, error)
+	// Capture takes an authorized payment. Capturing a payment again
+	// takes nothing more, so a failed capture can be retried.
+	Capture(paymentID string, amount int64) error
+	// Void releases an authorized payment that will not be captured.
+	// Voiding a payment again does nothing.
+	Void(paymentID string) error
+	// Refund pays amount of a captured payment back.
+	Refund(paymentID string, amount int64) error
+	// VerifyWebhook checks that a webhook came from the provider and
+	// returns the event it carries.
+	VerifyWebhook(header http.Header, body []byte) (*Event, error)
+}
+
+// FromEnv returns the provider named by PAYMENT_PROVIDER. There is no
+// default: the fake provider lets anyone complete payments, so it has to
+// be chosen on purpose. Webhooks are verified with PAYMENT_WEBHOOK_SECRET,
+// which must be set.
+func FromEnv() (Provider, error) {
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "":
+		return nil, ErrNoProvider
+	case FakeProviderName:
+		secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+		if secret == "" {
+			return nil, ErrNoWebhookSecret
+		}
+		return NewFakeProvider(secret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+}
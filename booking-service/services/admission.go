@@ -0,0 +1,319 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"booking-service/models"
+	"booking-service/utils"
+)
+
+var ErrStudioRequired = errors.New("studioId is required")
+
+// AdmissionError tells why a ticket was refused at the entrance. Reason is
+// one of the models.Reject* codes.
+type AdmissionError struct {
+	Reason  string
+	Message string
+}
+
+func (e *AdmissionError) Error() string {
+	return e.Message
+}
+
+// admissionOpensBefore is how long before a showtime starts its tickets are
+// first admitted.
+func admissionOpensBefore() time.Duration {
+	if v := os.Getenv("ADMISSION_OPENS_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return time.Hour
+}
+
+// admissionClosesAfter is how long after a showtime starts its tickets are
+// still admitted.
+func admissionClosesAfter() time.Duration {
+	if v := os.Getenv("ADMISSION_CLOSES_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return 30 * time.Minute
+}
+
+// admissionWindow returns when admission to a showtime starting at start
+// opens and closes.
+func admissionWindow(start time.Time) (opens, closes time.Time) {
+	return start.Add(-admissionOpensBefore()), start.Add(admissionClosesAfter())
+}
+
+// ticketRejection turns a failed ticket check into the reason it is
+// refused.
+func ticketRejection(err error) error {
+	switch {
+	case errors.Is(err, utils.ErrUnknownTicketKey):
+		return &AdmissionError{Reason: models.RejectUnknownKey, Message: err.Error()}
+	case errors.Is(err, utils.ErrTicketSignature):
+		return &AdmissionError{Reason: models.RejectBadSignature, Message: err.Error()}
+	case errors.Is(err, utils.ErrMalformedTicket):
+		return &AdmissionError{Reason: models.RejectMalformedTicket, Message: err.Error()}
+	}
+	return err
+}
+
+// checkStudio refuses tickets for another studio than the scanner's.
+func checkStudio(ticket utils.Ticket, studioID uint) error {
+	if ticket.StudioID != studioID {
+		return &AdmissionError{
+			Reason:  models.RejectWrongStudio,
+			Message: fmt.Sprintf("ticket is for studio %d", ticket.StudioID),
+		}
+	}
+	return nil
+}
+
+// reentryPolicy is whether and how seats may get in again after their
+// first admission: models.ReentryNone, ReentryExitScan or ReentryFree.
+func reentryPolicy() string {
+	switch v := os.Getenv("REENTRY_POLICY"); v {
+	case models.ReentryExitScan, models.ReentryFree:
+		return v
+	}
+	return models.ReentryNone
+}
+
+// reentryLimit is how many times a seat may get in again; 0 is no limit.
+func reentryLimit() int {
+	if v := os.Getenv("REENTRY_LIMIT"); v != "" {
+		if limit, err := strconv.Atoi(v); err == nil && limit >= 0 {
+			return limit
+		}
+	}
+	return 0
+}
+
+// reentryClosesAfter is how long after a showtime starts seats may still
+// get in again.
+func reentryClosesAfter() time.Duration {
+	if v := os.Getenv("REENTRY_CLOSES_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return 4 * time.Hour
+}
+
+// checkBookingStatus refuses bookings that cannot be admitted any more, or
+// not yet. Used bookings pass: whether their seats may get in again is up
+// to the seats.
+func checkBookingStatus(status models.BookingStatus) error {
+	switch status {
+	case models.BookingActive, models.BookingUsed:
+		return nil
+	case models.BookingCancelled:
+		return &AdmissionError{Reason: models.RejectCancelled, Message: "booking was cancelled"}
+	}
+	return &AdmissionError{Reason: models.RejectNotActive, Message: fmt.Sprintf("booking is %s", status)}
+}
+
+// checkAdmissionTime refuses scans outside the admission window of a
+// showtime starting at start.
+func checkAdmissionTime(start, at time.Time) error {
+	opens, closes := admissionWindow(start)
+	if at.Before(opens) {
+		return &AdmissionError{
+			Reason:  models.RejectTooEarly,
+			Message: "admission opens at " + opens.Format(time.RFC3339),
+		}
+	}
+	if at.After(closes) {
+		return &AdmissionError{
+			Reason:  models.RejectTooLate,
+			Message: "admission closed at " + closes.Format(time.RFC3339),
+		}
+	}
+	return nil
+}
+
+// checkSeatEntry refuses a seat that may not get in at at, for a showtime
+// starting at start. A seat's first admission keeps to the admission
+// window; getting in again keeps to the re-entry policy instead.
+func checkSeatEntry(item models.BookingItem, start, at time.Time) error {
+	if item.CancelledAt != nil {
+		return &AdmissionError{
+			Reason:  models.RejectSeatNotBooked,
+			Message: fmt.Sprintf("seat %d is not part of the booking", item.SeatID),
+		}
+	}
+	if item.AdmittedAt == nil {
+		return checkAdmissionTime(start, at)
+	}
+
+	policy := reentryPolicy()
+	switch {
+	case policy == models.ReentryNone:
+		return &AdmissionError{
+			Reason:  models.RejectAlreadyUsed,
+			Message: fmt.Sprintf("seat %s was already admitted", item.SeatNumber),
+		}
+	case policy == models.ReentryExitScan && item.Inside():
+		return &AdmissionError{
+			Reason:  models.RejectAlreadyInside,
+			Message: fmt.Sprintf("seat %s is already inside", item.SeatNumber),
+		}
+	}
+	if limit := reentryLimit(); limit > 0 && item.Reentries >= limit {
+		return &AdmissionError{
+			Reason:  models.RejectReentryLimit,
+			Message: fmt.Sprintf("seat %s may not get in again", item.SeatNumber),
+		}
+	}
+	if closes := start.Add(reentryClosesAfter()); at.After(closes) {
+		return &AdmissionError{
+			Reason:  models.RejectReentryClosed,
+			Message: "re-entry closed at " + closes.Format(time.RFC3339),
+		}
+	}
+	return nil
+}
+
+// seatItems picks the items of seatIDs out of a booking's items. Seats the
+// booking does not hold are refused.
+func seatItems(items []models.BookingItem, seatIDs []uint) ([]int, error) {
+	picked := make([]int, 0, len(seatIDs))
+	for _, seatID := range seatIDs {
+		found := -1
+		for i, item := range items {
+			if item.SeatID == seatID && item.CancelledAt == nil {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return nil, &AdmissionError{
+				Reason:  models.RejectSeatNotBooked,
+				Message: fmt.Sprintf("seat %d is not part of the booking", seatID),
+			}
+		}
+		picked = append(picked, found)
+	}
+	return picked, nil
+}
+
+// planEntry returns the indexes of the items that get in at at. Named seats
+// get in together or not at all. Without seats every seat outside that may
+// get in does; under free re-entry a booking whose seats are all inside is
+// let in again as a whole. When no seat gets in, the first refusal is
+// returned.
+func planEntry(items []models.BookingItem, seatIDs []uint, start, at time.Time) ([]int, error) {
+	if len(seatIDs) > 0 {
+		picked, err := seatItems(items, seatIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range picked {
+			if err := checkSeatEntry(items[i], start, at); err != nil {
+				return nil, err
+			}
+		}
+		return picked, nil
+	}
+
+	var candidates, inside []int
+	for i, item := range items {
+		switch {
+		case item.CancelledAt != nil:
+		case item.Inside():
+			inside = append(inside, i)
+		default:
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 && reentryPolicy() == models.ReentryFree {
+		candidates = inside
+	}
+
+	var admitted []int
+	var refusal error
+	for _, i := range candidates {
+		if err := checkSeatEntry(items[i], start, at); err != nil {
+			if refusal == nil {
+				refusal = err
+			}
+			continue
+		}
+		admitted = append(admitted, i)
+	}
+	if len(admitted) > 0 {
+		return admitted, nil
+	}
+	if refusal != nil {
+		return nil, refusal
+	}
+	if len(inside) > 0 && reentryPolicy() == models.ReentryExitScan {
+		return nil, &AdmissionError{Reason: models.RejectAlreadyInside, Message: "every seat is already inside"}
+	}
+	return nil, &AdmissionError{Reason: models.RejectAlreadyUsed, Message: "ticket was already admitted"}
+}
+
+// planExit returns the indexes of the items that leave: the named seats,
+// which must all be inside, or without seats every seat inside.
+func planExit(items []models.BookingItem, seatIDs []uint) ([]int, error) {
+	if len(seatIDs) > 0 {
+		picked, err := seatItems(items, seatIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range picked {
+			if !items[i].Inside() {
+				return nil, &AdmissionError{
+					Reason:  models.RejectNotInside,
+					Message: fmt.Sprintf("seat %s is not inside", items[i].SeatNumber),
+				}
+			}
+		}
+		return picked, nil
+	}
+
+	var leaving []int
+	for i, item := range items {
+		if item.CancelledAt == nil && item.Inside() {
+			leaving = append(leaving, i)
+		}
+	}
+	if len(leaving) == 0 {
+		return nil, &AdmissionError{Reason: models.RejectNotInside, Message: "no seat of the booking is inside"}
+	}
+	return leaving, nil
+}
+
+// enterSeat records a seat getting in at at.
+func enterSeat(item *models.BookingItem, at time.Time) {
+	if item.AdmittedAt == nil {
+		item.AdmittedAt = &at
+	} else {
+		item.Reentries++
+	}
+	item.LastEntryAt = &at
+	item.ExitedAt = nil
+}
+
+// showtimeCache looks showtimes up in cinema service once each.
+type showtimeCache map[uint]*utils.Showtime
+
+func (c showtimeCache) get(id uint) (*utils.Showtime, error) {
+	if showtime, ok := c[id]; ok {
+		return showtime, nil
+	}
+	showtime, err := utils.GetShowtime(id)
+	if err != nil {
+		return nil, err
+	}
+	c[id] = showtime
+	return showtime, nil
+}
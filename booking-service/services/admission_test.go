@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"booking-service/models"
+	"booking-service/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rejectionReason returns the reason code of a refusal, or "" for nil.
+func rejectionReason(t *testing.T, err error) string {
+	if err == nil {
+		return ""
+	}
+	var refused *AdmissionError
+	if !errors.As(err, &refused) {
+		t.Fatalf("expected an AdmissionError, got %v", err)
+	}
+	return refused.Reason
+}
+
+func TestCheckAdmissionTime(t *testing.T) {
+	start := time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		opens  string
+		closes string
+		want   string
+	}{
+		{"at the start", start, "", "", ""},
+		{"as admission opens", start.Add(-time.Hour), "", "", ""},
+		{"before admission opens", start.Add(-time.Hour - time.Second), "", "", models.RejectTooEarly},
+		{"as admission closes", start.Add(30 * time.Minute), "", "", ""},
+		{"after admission closes", start.Add(31 * time.Minute), "", "", models.RejectTooLate},
+		{"the day before", start.AddDate(0, 0, -1), "", "", models.RejectTooEarly},
+		{"with a shorter window", start.Add(-20 * time.Minute), "15", "", models.RejectTooEarly},
+		{"with a longer window", start.Add(45 * time.Minute), "", "60", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ADMISSION_OPENS_MINUTES", tt.opens)
+			t.Setenv("ADMISSION_CLOSES_MINUTES", tt.closes)
+			assert.Equal(t, tt.want, rejectionReason(t, checkAdmissionTime(start, tt.at)))
+		})
+	}
+}
+
+func TestCheckBookingStatus(t *testing.T) {
+	tests := []struct {
+		status models.BookingStatus
+		want   string
+	}{
+		{models.BookingActive, ""},
+		{models.BookingUsed, ""},
+		{models.BookingCancelled, models.RejectCancelled},
+		{models.BookingPendingPayment, models.RejectNotActive},
+		{models.BookingExpired, models.RejectNotActive},
+		{models.BookingNoShow, models.RejectNotActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			assert.Equal(t, tt.want, rejectionReason(t, checkBookingStatus(tt.status)))
+		})
+	}
+}
+
+func TestCheckStudio(t *testing.T) {
+	ticket := utils.Ticket{BookingCode: "BOOK123", StudioID: 5}
+
+	assert.NoError(t, checkStudio(ticket, 5))
+	assert.Equal(t, models.RejectWrongStudio, rejectionReason(t, checkStudio(ticket, 0)))
+	assert.Equal(t, models.RejectWrongStudio, rejectionReason(t, checkStudio(ticket, 1)))
+}
+
+func TestTicketRejection(t *testing.T) {
+	assert.Equal(t, models.RejectMalformedTicket, rejectionReason(t, ticketRejection(utils.ErrMalformedTicket)))
+	assert.Equal(t, models.RejectUnknownKey, rejectionReason(t, ticketRejection(utils.ErrUnknownTicketKey)))
+	assert.Equal(t, models.RejectBadSignature, rejectionReason(t, ticketRejection(utils.ErrTicketSignature)))
+}
+
+func TestApplyScanResult(t *testing.T) {
+	var event models.ScanEvent
+	assert.NoError(t, applyScanResult(&event, nil))
+	assert.Equal(t, models.ScanAdmitted, event.Result)
+
+	event = models.ScanEvent{}
+	assert.NoError(t, applyScanResult(&event, checkBookingStatus(models.BookingCancelled)))
+	assert.Equal(t, models.ScanRejected, event.Result)
+	assert.Equal(t, models.RejectCancelled, event.Reason)
+
+	failure := errors.New("database is down")
+	assert.Equal(t, failure, applyScanResult(&models.ScanEvent{}, failure))
+}
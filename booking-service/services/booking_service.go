@@ -1,24 +1,33 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"booking-service/database"
 	"booking-service/models"
 	"booking-service/utils"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func CreateOnlineBooking(req models.OnlineBookingRequest, user models.User) (*models.Booking, error) {
-	return createBooking(req.StudioID, req.SeatIDs, &user.ID, user.Name, user.Email, "online")
+func CreateOnlineBooking(req models.OnlineBookingRequest, user models.User) (*models.Booking, []utils.SeatGap, error) {
+	return createBooking(req.StudioID, req.ShowtimeID, req.SeatIDs, req.Tickets, req.PromoCode, &user.ID, user.Name, user.Email, "online")
 }
 
-func CreateOfflineBooking(req models.OfflineBookingRequest) (*models.Booking, error) {
-	return createBooking(req.StudioID, req.SeatIDs, nil, req.CustomerName, req.CustomerEmail, "offline")
+func CreateOfflineBooking(req models.OfflineBookingRequest) (*models.Booking, []utils.SeatGap, error) {
+	return createBooking(req.StudioID, req.ShowtimeID, req.SeatIDs, req.Tickets, req.PromoCode, nil, req.CustomerName, req.CustomerEmail, "offline")
 }
 
-func createBooking(studioID uint, seatIDs []uint, userID *uint, userName, userEmail, bookingType string) (*models.Booking, error) {
+func createBooking(studioID, showtimeID uint, seatIDs []uint, tickets []models.TicketRequest, promoCode string, userID *uint, userName, userEmail, bookingType string) (*models.Booking, []utils.SeatGap, error) {
+	categories, err := ticketCategories(seatIDs, tickets)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Start transaction
 	tx := database.DB.Begin()
 	defer func() {
@@ -27,21 +36,30 @@ func createBooking(studioID uint, seatIDs []uint, userID *uint, userName, userEm
 		}
 	}()
 
-	// Reserve seats in cinema service
-	err := utils.ReserveSeats(seatIDs)
+	// Hold seats in cinema service; the hold expires on its own if this
+	// process dies before the sale is confirmed.
+	holdToken, gapWarnings, err := utils.HoldSeats(studioID, showtimeID, seatIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	// Price the seats once cinema service has accepted them
+	showtime, items, total, err := quoteTickets(studioID, showtimeID, seatIDs, categories)
 	if err != nil {
 		tx.Rollback()
-		return nil, err
+		utils.ReleaseHold(holdToken)
+		return nil, nil, err
 	}
 
 	bookingCode := uuid.New().String()
 	
 	// Generate QR code
-	qrCode, err := utils.GenerateQRCode(bookingCode, studioID, seatIDs, userID, userName)
+	qrCode, err := utils.GenerateQRCode(bookingCode, studioID, showtimeID, seatIDs, userID, userName)
 	if err != nil {
 		tx.Rollback()
-		utils.ReleaseSeats(seatIDs)
-		return nil, fmt.Errorf("failed to generate QR code")
+		utils.ReleaseHold(holdToken)
+		return nil, nil, fmt.Errorf("failed to generate QR code")
 	}
 
 	// Convert []uint to pq.Int64Array
@@ -57,28 +75,113 @@ func createBooking(studioID uint, seatIDs []uint, userID *uint, userName, userEm
 		UserName:    userName,
 		UserEmail:   userEmail,
 		StudioID:    studioID,
+		ShowtimeID:  showtimeID,
 		SeatIDs:     seatIDsInt64,
+		HoldToken:   holdToken,
 		QRCode:      qrCode,
 		BookingType: bookingType,
-		Status:      "active",
+		Status:      models.BookingActive,
+		TotalAmount: total,
+		Currency:    priceCurrency(),
+		Items:       items,
+	}
+
+	var promo *models.PromoCode
+	if promoCode != "" {
+		promo, booking.Discount, err = applyPromoCode(tx, promoCode, &booking, *showtime)
+		if err != nil {
+			tx.Rollback()
+			utils.ReleaseHold(holdToken)
+			return nil, nil, err
+		}
+		booking.PromoCode = promo.Code
+		booking.TotalAmount -= booking.Discount
+	}
+
+	// Online customers pay before the seats are sold; the payment webhook
+	// confirms the hold once the money is in.
+	needsPayment := bookingType == "online" && booking.TotalAmount > 0
+	if needsPayment {
+		booking.Status = models.BookingPendingPayment
 	}
 
 	result := tx.Create(&booking)
 	if result.Error != nil {
 		tx.Rollback()
-		utils.ReleaseSeats(seatIDs)
-		return nil, fmt.Errorf("failed to create booking")
+		utils.ReleaseHold(holdToken)
+		return nil, nil, fmt.Errorf("failed to create booking")
+	}
+	if err := recordBookingStatus(tx, booking.ID, "", booking.Status, nil, ""); err != nil {
+		tx.Rollback()
+		utils.ReleaseHold(holdToken)
+		return nil, nil, fmt.Errorf("failed to create booking")
+	}
+
+	if promo != nil {
+		if err := redeemPromoCode(tx, promo, &booking); err != nil {
+			tx.Rollback()
+			utils.ReleaseHold(holdToken)
+			return nil, nil, err
+		}
+	}
+
+	if needsPayment {
+		if err := startPayment(tx, &booking); err != nil {
+			tx.Rollback()
+			utils.ReleaseHold(holdToken)
+			return nil, nil, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			utils.ReleaseHold(holdToken)
+			return nil, nil, fmt.Errorf("failed to commit transaction")
+		}
+		return &booking, gapWarnings, nil
+	}
+
+	// Turn the hold into a sale before the booking becomes visible
+	if err := utils.ConfirmHold(holdToken); err != nil {
+		tx.Rollback()
+		utils.ReleaseHold(holdToken)
+		return nil, nil, err
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		utils.ReleaseSeats(seatIDs)
-		return nil, fmt.Errorf("failed to commit transaction")
+		utils.ReleaseSeats(showtimeID, seatIDs)
+		return nil, nil, fmt.Errorf("failed to commit transaction")
 	}
 
-	return &booking, nil
+	return &booking, gapWarnings, nil
 }
 
-func ValidateQRCode(bookingCode string) (*models.Booking, error) {
+// ValidateQRCode admits a scanned ticket at the entrance of a studio. The
+// ticket's signature and studio are checked before the booking is looked
+// up, so forged, edited or misdirected tickets never reach the database.
+// Seats get in one by one: a seat's first admission must fall in its
+// showtime's admission window, and getting in again follows the re-entry
+// policy. It returns the booking and the seats that got in. Refusals are
+// AdmissionErrors. Every attempt is logged as a scan with the staff account
+// of actor, so staff can follow a booking's admissions, managers can audit
+// the entrances and offline scanners uploading later can tell duplicates.
+func ValidateQRCode(req models.ValidateQRRequest, actor *models.User) (*models.Booking, []uint, error) {
+	if req.StudioID == 0 {
+		return nil, nil, ErrStudioRequired
+	}
+	scannedAt := time.Now()
+	event := scanAttempt(req, models.ScanEntry, scannedAt, actor)
+
+	ticket, err := utils.VerifyTicket(req.Ticket)
+	if err != nil {
+		err = ticketRejection(err)
+		recordRefusedScan(event, err)
+		return nil, nil, err
+	}
+	event.BookingCode = ticket.BookingCode
+	event.ShowtimeID = ticket.ShowtimeID
+	if err := checkStudio(*ticket, req.StudioID); err != nil {
+		recordRefusedScan(event, err)
+		return nil, nil, err
+	}
+
 	tx := database.DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -87,29 +190,65 @@ func ValidateQRCode(bookingCode string) (*models.Booking, error) {
 	}()
 
 	var booking models.Booking
-	result := tx.Where("booking_code = ? AND status = ?", bookingCode, "active").First(&booking)
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("booking_code = ?", ticket.BookingCode).First(&booking)
 	if result.Error != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("invalid or used ticket")
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			err := &AdmissionError{Reason: models.RejectNotFound, Message: "invalid or used ticket"}
+			recordRefusedScan(event, err)
+			return nil, nil, err
+		}
+		return nil, nil, result.Error
+	}
+	event.BookingID = &booking.ID
+	event.ShowtimeID = booking.ShowtimeID
+	if err := checkBookingStatus(booking.Status); err != nil {
+		tx.Rollback()
+		recordRefusedScan(event, err)
+		return nil, nil, err
 	}
 
-	// Mark as used
-	result = tx.Model(&booking).Update("status", "used")
-	if result.Error != nil {
+	showtime, err := utils.GetShowtime(booking.ShowtimeID)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	reason := ""
+	if req.DeviceID != "" {
+		reason = "scanned by " + req.DeviceID
+	}
+	admitted, err := admitSeats(tx, &booking, req.SeatIDs, showtime.StartTime, scannedAt, actor, reason)
+	if err != nil {
+		tx.Rollback()
+		recordRefusedScan(event, err)
+		if errors.Is(err, ErrInvalidTransition) {
+			return nil, nil, err
+		}
+		var refused *AdmissionError
+		if errors.As(err, &refused) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to update booking status")
+	}
+
+	event.SeatIDs = toInt64Array(admitted)
+	event.Result = models.ScanAdmitted
+	if result := tx.Create(&event); result.Error != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("failed to update booking status")
+		return nil, nil, fmt.Errorf("failed to update booking status")
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction")
+		return nil, nil, fmt.Errorf("failed to commit transaction")
 	}
 
-	return &booking, nil
+	return &booking, admitted, nil
 }
 
 func GetUserBookings(userID uint) ([]models.Booking, error) {
 	var bookings []models.Booking
-	result := database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&bookings)
+	result := database.DB.Preload("Items").Preload("Payments").Where("user_id = ?", userID).Order("created_at DESC").Find(&bookings)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidTransition  = errors.New("booking cannot move to this status")
+	ErrShowtimeNotStarted = errors.New("bookings can only be marked as no-shows once the showtime has started")
+)
+
+// TransitionError tells which move between statuses a booking refused. It
+// matches ErrInvalidTransition.
+type TransitionError struct {
+	From models.BookingStatus
+	To   models.BookingStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("booking is %s and cannot become %s", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// recordBookingStatus adds a status change to a booking's history. actor is
+// nil for changes the service makes by itself.
+func recordBookingStatus(tx *gorm.DB, bookingID uint, from, to models.BookingStatus, actor *models.User, reason string) error {
+	change := models.BookingStatusChange{
+		BookingID:  bookingID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+	}
+	if actor != nil {
+		change.ActorID = &actor.ID
+		change.ActorRole = actor.Role
+	}
+	return tx.Create(&change).Error
+}
+
+// transitionBooking moves a booking to another status and records the
+// change. Every status change goes through here, so the allowed moves are
+// only checked in one place. The update only applies while the booking
+// still has the status it was loaded with, so a concurrent change makes it
+// fail rather than be overwritten.
+func transitionBooking(tx *gorm.DB, booking *models.Booking, to models.BookingStatus, actor *models.User, reason string) error {
+	from := booking.Status
+	if !from.CanBecome(to) {
+		return &TransitionError{From: from, To: to}
+	}
+
+	result := tx.Model(&models.Booking{}).
+		Where("id = ? AND status = ?", booking.ID, from).
+		Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var current models.Booking
+		if err := tx.Select("status").First(&current, booking.ID).Error; err != nil {
+			return err
+		}
+		return &TransitionError{From: current.Status, To: to}
+	}
+
+	booking.Status = to
+	return recordBookingStatus(tx, booking.ID, from, to, actor, reason)
+}
+
+// GetBookingHistory lists the status changes of a booking the actor may
+// see, oldest first.
+func GetBookingHistory(id uint, actor models.User) ([]models.BookingStatusChange, error) {
+	var booking models.Booking
+	if err := database.DB.First(&booking, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+	if !canCancel(booking, actor) {
+		return nil, ErrBookingForbidden
+	}
+
+	history := []models.BookingStatusChange{}
+	err := database.DB.Where("booking_id = ?", booking.ID).Order("created_at, id").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// MarkNoShow records that nobody came for an active booking. Staff can only
+// do so once the showtime has started; the seats stay sold.
+func MarkNoShow(id uint, actor models.User) (*models.Booking, error) {
+	var booking models.Booking
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBookingNotFound
+			}
+			return err
+		}
+		if !booking.Status.CanBecome(models.BookingNoShow) {
+			return &TransitionError{From: booking.Status, To: models.BookingNoShow}
+		}
+
+		showtime, err := utils.GetShowtime(booking.ShowtimeID)
+		if err != nil {
+			return err
+		}
+		if time.Now().Before(showtime.StartTime) {
+			return ErrShowtimeNotStarted
+		}
+
+		return transitionBooking(tx, &booking, models.BookingNoShow, &actor, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"booking-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingTransitions(t *testing.T) {
+	tests := []struct {
+		from models.BookingStatus
+		to   models.BookingStatus
+		want bool
+	}{
+		{models.BookingPendingPayment, models.BookingPaid, true},
+		{models.BookingPendingPayment, models.BookingPaymentFailed, true},
+		{models.BookingPendingPayment, models.BookingCancelled, true},
+		{models.BookingPendingPayment, models.BookingExpired, true},
+		{models.BookingPendingPayment, models.BookingActive, false},
+		{models.BookingPaid, models.BookingActive, true},
+		{models.BookingPaid, models.BookingRefunded, true},
+		{models.BookingPaid, models.BookingCancelled, false},
+		{models.BookingActive, models.BookingUsed, true},
+		{models.BookingActive, models.BookingCancelled, true},
+		{models.BookingActive, models.BookingNoShow, true},
+		{models.BookingActive, models.BookingPendingPayment, false},
+		{models.BookingUsed, models.BookingUsed, false},
+		{models.BookingUsed, models.BookingCancelled, false},
+		{models.BookingCancelled, models.BookingActive, false},
+		{models.BookingExpired, models.BookingPaid, false},
+		{models.BookingNoShow, models.BookingUsed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.from.CanBecome(tt.to))
+		})
+	}
+}
+
+func TestTransitionBookingRefusesInvalidMoves(t *testing.T) {
+	booking := models.Booking{ID: 1, Status: models.BookingUsed}
+
+	// Refused moves never reach the database.
+	err := transitionBooking(nil, &booking, models.BookingCancelled, nil, "")
+
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	assert.EqualError(t, err, "booking is used and cannot become cancelled")
+	assert.Equal(t, models.BookingUsed, booking.Status)
+}
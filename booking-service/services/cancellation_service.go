@@ -0,0 +1,446 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBookingNotFound       = errors.New("booking not found")
+	ErrBookingForbidden      = errors.New("you can only change your own bookings")
+	ErrBookingNotCancellable = errors.New("only active bookings can drop seats")
+	ErrCancellationClosed    = errors.New("cancellations have closed for this showtime")
+	ErrNoSeatsToCancel       = errors.New("seatIds must contain at least one seat")
+	ErrSeatsNotInBooking     = errors.New("some seats are not part of this booking")
+	ErrCancelEverySeat       = errors.New("cancel the booking to drop every seat")
+	ErrRefundFailed          = errors.New("the payment provider refused the refund; nothing was changed")
+)
+
+// Roles that may cancel any booking.
+var staffRoles = map[string]bool{"admin": true, "cashier": true}
+
+// cancelCutoff is how long before the showtime cancellations close.
+func cancelCutoff() time.Duration {
+	if v := os.Getenv("CANCEL_CUTOFF_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return 2 * time.Hour
+}
+
+// fullRefundWindow is how long before the showtime a cancellation still
+// gets all its money back.
+func fullRefundWindow() time.Duration {
+	if v := os.Getenv("CANCEL_FULL_REFUND_HOURS"); v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours >= 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return 24 * time.Hour
+}
+
+// partialRefundPercent is the share refunded for cancellations after the
+// full refund window.
+func partialRefundPercent() int64 {
+	if v := os.Getenv("CANCEL_PARTIAL_REFUND_PERCENT"); v != "" {
+		if percent, err := strconv.Atoi(v); err == nil && percent >= 0 && percent <= 100 {
+			return int64(percent)
+		}
+	}
+	return 50
+}
+
+// refundFor applies the refund policy to a paid amount, given how long
+// before the showtime the booking is cancelled.
+func refundFor(paid int64, timeLeft time.Duration) (string, int64) {
+	if paid <= 0 {
+		return models.RefundNone, 0
+	}
+	if timeLeft >= fullRefundWindow() {
+		return models.RefundFull, paid
+	}
+	if percent := partialRefundPercent(); percent > 0 {
+		return models.RefundPartial, paid * percent / 100
+	}
+	return models.RefundNone, 0
+}
+
+// canCancel tells whether actor may cancel booking: customers their own
+// bookings, cashiers and admins any booking.
+func canCancel(booking models.Booking, actor models.User) bool {
+	if staffRoles[actor.Role] {
+		return true
+	}
+	return booking.UserID != nil && *booking.UserID == actor.ID
+}
+
+// capturedPayment returns the payment money was taken with, if any.
+func capturedPayment(tx *gorm.DB, bookingID uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := tx.Where("booking_id = ? AND status = ?", bookingID, models.PaymentCaptured).First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// lockBookingForChange locks a booking the actor may cancel or change into
+// booking and returns how long is left before its showtime, refusing once
+// the cutoff has passed.
+func lockBookingForChange(tx *gorm.DB, id uint, actor models.User, booking *models.Booking, now time.Time) (time.Duration, error) {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrBookingNotFound
+		}
+		return 0, err
+	}
+	return changeWindow(*booking, actor, now)
+}
+
+// changeWindow checks that actor may change booking and returns how long
+// is left before its showtime, refusing once the cutoff has passed.
+func changeWindow(booking models.Booking, actor models.User, now time.Time) (time.Duration, error) {
+	if !canCancel(booking, actor) {
+		return 0, ErrBookingForbidden
+	}
+
+	showtime, err := utils.GetShowtime(booking.ShowtimeID)
+	if err != nil {
+		return 0, err
+	}
+	timeLeft := showtime.StartTime.Sub(now)
+	if timeLeft < cancelCutoff() {
+		return 0, ErrCancellationClosed
+	}
+	return timeLeft, nil
+}
+
+// refundPayment pays amount of a booking's captured payment back through
+// the payment provider and returns the payment when it did. Bookings
+// without one were paid at the till. The provider is asked last, while the
+// transaction is still open, so a refund it refuses leaves the booking as
+// it was. If the transaction then fails to commit, the money has been paid
+// back for a change that was not saved; callers report that with
+// logUnsavedRefund.
+func refundPayment(tx *gorm.DB, bookingID uint, amount int64) (*models.Payment, error) {
+	if amount == 0 {
+		return nil, nil
+	}
+	payment, err := capturedPayment(tx, bookingID)
+	if err != nil || payment == nil {
+		return nil, err
+	}
+	if paymentProvider == nil {
+		return nil, ErrPaymentsUnavailable
+	}
+
+	// A payment stays captured until all of it has been paid back.
+	payment.RefundedAmount += amount
+	if payment.RefundedAmount >= payment.Amount {
+		payment.Status = models.PaymentRefunded
+	}
+	err = tx.Model(payment).Updates(map[string]interface{}{
+		"status":          payment.Status,
+		"refunded_amount": payment.RefundedAmount,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := paymentProvider.Refund(payment.PaymentID, amount); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRefundFailed, err)
+	}
+	return payment, nil
+}
+
+// claimRefund records amount of a booking's captured payment as owed back
+// before the payment provider is asked, the way a capture is claimed before
+// the money is taken. It returns the payment, or nil for bookings paid at
+// the till. settleRefund pays it once the transaction has committed.
+func claimRefund(tx *gorm.DB, bookingID uint, amount int64) (*models.Payment, error) {
+	if amount == 0 {
+		return nil, nil
+	}
+	payment, err := capturedPayment(tx, bookingID)
+	if err != nil || payment == nil {
+		return nil, err
+	}
+	if paymentProvider == nil {
+		return nil, ErrPaymentsUnavailable
+	}
+
+	payment.PendingRefund += amount
+	if err := tx.Model(payment).Update("pending_refund", payment.PendingRefund).Error; err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// settleRefund pays a claimed refund back through the payment provider and
+// records it. A refund the provider refuses, or that is paid but cannot be
+// recorded, stays pending on the payment and is logged so staff can put
+// it right.
+func settleRefund(payment *models.Payment, amount int64) {
+	if err := paymentProvider.Refund(payment.PaymentID, amount); err != nil {
+		log.Printf("Refund of %d of payment %s is still pending: %v", amount, payment.PaymentID, err)
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, payment.ID).Error; err != nil {
+			return err
+		}
+		// A payment stays captured until all of it has been paid back.
+		locked.PendingRefund -= amount
+		locked.RefundedAmount += amount
+		if locked.RefundedAmount >= locked.Amount {
+			locked.Status = models.PaymentRefunded
+		}
+		err := tx.Model(&locked).Updates(map[string]interface{}{
+			"status":          locked.Status,
+			"pending_refund":  locked.PendingRefund,
+			"refunded_amount": locked.RefundedAmount,
+		}).Error
+		if err != nil {
+			return err
+		}
+		*payment = locked
+		return nil
+	})
+	if err != nil {
+		log.Printf("Refunded %d of payment %s but left it pending: %v", amount, payment.PaymentID, err)
+	}
+}
+
+// logUnsavedRefund reports a refund the provider made for a change that
+// then failed to save, so staff can put the payment right by hand.
+func logUnsavedRefund(payment *models.Payment, amount int64, err error) {
+	if payment != nil {
+		log.Printf("Refunded %d of payment %s but the booking change was not saved: %v", amount, payment.PaymentID, err)
+	}
+}
+
+// CancelBooking cancels a booking up to the cutoff before its showtime,
+// refunds it under the refund policy and gives its seats back to cinema
+// service. Bookings paid at the till are refunded there; the booking
+// records how much. Bookings still waiting for their payment have it
+// voided instead, unless it is already being taken.
+func CancelBooking(id uint, actor models.User, reason string) (*models.Booking, error) {
+	var booking models.Booking
+	var previousStatus models.BookingStatus
+	var refunded *models.Payment
+	var refund int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		timeLeft, err := lockBookingForChange(tx, id, actor, &booking, now)
+		if err != nil {
+			return err
+		}
+		// Unpaid bookings have nothing to refund.
+		paid := booking.TotalAmount
+		if booking.Status == models.BookingPendingPayment {
+			paid = 0
+			if err := voidPayments(tx, booking.ID); err != nil {
+				return err
+			}
+		}
+		var policy string
+		policy, refund = refundFor(paid, timeLeft)
+
+		previousStatus = booking.Status
+		if err := transitionBooking(tx, &booking, models.BookingCancelled, &actor, reason); err != nil {
+			return err
+		}
+		booking.CancelledAt = &now
+		booking.CancelledBy = &actor.ID
+		booking.CancelledByRole = actor.Role
+		booking.CancelReason = reason
+		booking.RefundPolicy = policy
+		// Seats cancelled or exchanged earlier may have been refunded
+		// already.
+		booking.RefundAmount += refund
+		err = tx.Model(&booking).
+			Select("cancelled_at", "cancelled_by", "cancelled_by_role", "cancel_reason", "refund_policy", "refund_amount").
+			Updates(&booking).Error
+		if err != nil {
+			return err
+		}
+		if err := releasePromoCode(tx, booking.ID); err != nil {
+			return err
+		}
+
+		refunded, err = refundPayment(tx, booking.ID, refund)
+		return err
+	})
+	if err != nil {
+		logUnsavedRefund(refunded, refund, err)
+		return nil, err
+	}
+
+	if previousStatus == models.BookingPendingPayment {
+		utils.ReleaseHold(booking.HoldToken)
+	} else {
+		seatIDs := make([]uint, len(booking.SeatIDs))
+		for i, id := range booking.SeatIDs {
+			seatIDs[i] = uint(id)
+		}
+		utils.ReleaseSeats(booking.ShowtimeID, seatIDs)
+	}
+	return &booking, nil
+}
+
+// splitBookingSeats checks the seats to drop against a booking's seats and
+// returns the seats it keeps.
+func splitBookingSeats(booked []int64, drop []uint) ([]uint, error) {
+	if len(drop) == 0 {
+		return nil, ErrNoSeatsToCancel
+	}
+
+	dropping := make(map[uint]bool, len(drop))
+	for _, id := range drop {
+		dropping[id] = true
+	}
+	var kept []uint
+	for _, id := range booked {
+		if dropping[uint(id)] {
+			delete(dropping, uint(id))
+			continue
+		}
+		kept = append(kept, uint(id))
+	}
+
+	if len(dropping) > 0 {
+		return nil, ErrSeatsNotInBooking
+	}
+	if len(kept) == 0 {
+		return nil, ErrCancelEverySeat
+	}
+	return kept, nil
+}
+
+// repriceBooking works out what the tickets a booking keeps cost, promo
+// code included.
+func repriceBooking(tx *gorm.DB, booking models.Booking, kept []models.BookingItem) (discount, total int64, err error) {
+	for _, item := range kept {
+		total += item.Price
+	}
+	if booking.PromoCode == "" {
+		return 0, total, nil
+	}
+
+	var promo models.PromoCode
+	if err := tx.Unscoped().Where("code = ?", booking.PromoCode).First(&promo).Error; err != nil {
+		return 0, 0, err
+	}
+	discount = promoDiscount(promo, kept)
+	return discount, total - discount, nil
+}
+
+// CancelSeats drops some seats from an active booking. The seats go back
+// on sale, the booking is repriced and the difference refunded under the
+// refund policy. The booking gets a new code and QR code so tickets issued
+// before no longer admit anyone.
+func CancelSeats(id uint, actor models.User, seatIDs []uint) (*models.Booking, error) {
+	var booking models.Booking
+	var refunded *models.Payment
+	var refund int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		timeLeft, err := lockBookingForChange(tx, id, actor, &booking, now)
+		if err != nil {
+			return err
+		}
+		if booking.Status != models.BookingActive {
+			return ErrBookingNotCancellable
+		}
+
+		keptSeats, err := splitBookingSeats(booking.SeatIDs, seatIDs)
+		if err != nil {
+			return err
+		}
+
+		var items []models.BookingItem
+		if err := tx.Where("booking_id = ? AND cancelled_at IS NULL", booking.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		keeping := make(map[uint]bool, len(keptSeats))
+		for _, seatID := range keptSeats {
+			keeping[seatID] = true
+		}
+		var keptItems []models.BookingItem
+		for _, item := range items {
+			if keeping[item.SeatID] {
+				keptItems = append(keptItems, item)
+			}
+		}
+
+		discount, total, err := repriceBooking(tx, booking, keptItems)
+		if err != nil {
+			return err
+		}
+		difference := booking.TotalAmount - total
+		if difference < 0 {
+			difference = 0
+		}
+		var policy string
+		policy, refund = refundFor(difference, timeLeft)
+
+		bookingCode := uuid.New().String()
+		qrCode, err := utils.GenerateQRCode(bookingCode, booking.StudioID, booking.ShowtimeID, keptSeats, booking.UserID, booking.UserName)
+		if err != nil {
+			return fmt.Errorf("failed to generate QR code")
+		}
+
+		err = tx.Model(&models.BookingItem{}).
+			Where("booking_id = ? AND seat_id IN ? AND cancelled_at IS NULL", booking.ID, seatIDs).
+			Update("cancelled_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		booking.BookingCode = bookingCode
+		booking.QRCode = qrCode
+		booking.SeatIDs = toInt64Array(keptSeats)
+		booking.Discount = discount
+		booking.TotalAmount = total
+		booking.RefundPolicy = policy
+		booking.RefundAmount += refund
+		err = tx.Model(&booking).
+			Select("booking_code", "qr_code", "seat_ids", "discount", "total_amount", "refund_policy", "refund_amount").
+			Updates(&booking).Error
+		if err != nil {
+			return err
+		}
+
+		refunded, err = refundPayment(tx, booking.ID, refund)
+		return err
+	})
+	if err != nil {
+		logUnsavedRefund(refunded, refund, err)
+		return nil, err
+	}
+
+	utils.ReleaseSeats(booking.ShowtimeID, seatIDs)
+
+	if err := database.DB.Where("booking_id = ?", booking.ID).Find(&booking.Items).Error; err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"booking-service/models"
+	"booking-service/payments"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefundFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		percent    string
+		paid       int64
+		timeLeft   time.Duration
+		wantPolicy string
+		wantRefund int64
+	}{
+		{"well ahead", "", 2400, 48 * time.Hour, models.RefundFull, 2400},
+		{"exactly at the full refund window", "", 2400, 24 * time.Hour, models.RefundFull, 2400},
+		{"same day", "", 2400, 5 * time.Hour, models.RefundPartial, 1200},
+		{"custom partial share", "25", 2400, 5 * time.Hour, models.RefundPartial, 600},
+		{"no partial refunds", "0", 2400, 5 * time.Hour, models.RefundNone, 0},
+		{"nothing paid", "", 0, 48 * time.Hour, models.RefundNone, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CANCEL_PARTIAL_REFUND_PERCENT", tt.percent)
+
+			policy, refund := refundFor(tt.paid, tt.timeLeft)
+			assert.Equal(t, tt.wantPolicy, policy)
+			assert.Equal(t, tt.wantRefund, refund)
+		})
+	}
+}
+
+func TestCanCancel(t *testing.T) {
+	owner := uint(7)
+	online := models.Booking{UserID: &owner}
+	offline := models.Booking{}
+
+	tests := []struct {
+		name    string
+		booking models.Booking
+		actor   models.User
+		want    bool
+	}{
+		{"own booking", online, models.User{ID: 7, Role: "customer"}, true},
+		{"someone else's booking", online, models.User{ID: 8, Role: "customer"}, false},
+		{"offline booking as customer", offline, models.User{ID: 7, Role: "customer"}, false},
+		{"cashier", online, models.User{ID: 2, Role: "cashier"}, true},
+		{"admin on offline booking", offline, models.User{ID: 1, Role: "admin"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, canCancel(tt.booking, tt.actor))
+		})
+	}
+}
+
+func TestSplitBookingSeats(t *testing.T) {
+	booked := []int64{4, 5, 6}
+
+	tests := []struct {
+		name     string
+		drop     []uint
+		wantKept []uint
+		wantErr  error
+	}{
+		{"drop one", []uint{5}, []uint{4, 6}, nil},
+		{"drop two", []uint{6, 4}, []uint{5}, nil},
+		{"drop nothing", nil, nil, ErrNoSeatsToCancel},
+		{"seat from elsewhere", []uint{5, 9}, nil, ErrSeatsNotInBooking},
+		{"every seat", []uint{4, 5, 6}, nil, ErrCancelEverySeat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, err := splitBookingSeats(booked, tt.drop)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantKept, kept)
+		})
+	}
+}
+
+func TestSettleRefundRefused(t *testing.T) {
+	UsePaymentProvider(payments.NewFakeProvider("secret"))
+	defer UsePaymentProvider(nil)
+
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	// The provider never took this payment, so it refuses the refund and
+	// the claim stays pending without touching the database.
+	payment := &models.Payment{PaymentID: "fake_missing", Amount: 2400, Status: models.PaymentCaptured, PendingRefund: 800}
+	settleRefund(payment, 800)
+	assert.Equal(t, int64(800), payment.PendingRefund)
+	assert.Equal(t, int64(0), payment.RefundedAmount)
+	assert.Contains(t, output.String(), "Refund of 800 of payment fake_missing is still pending")
+}
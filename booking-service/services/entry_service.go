@@ -0,0 +1,257 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// admittedColumns are the columns of a booking item a scan changes.
+var admittedColumns = []string{"admitted_at", "last_entry_at", "exited_at", "reentries"}
+
+// bookingSeatIDs returns the seats a booking holds.
+func bookingSeatIDs(booking models.Booking) []uint {
+	seatIDs := make([]uint, len(booking.SeatIDs))
+	for i, id := range booking.SeatIDs {
+		seatIDs[i] = uint(id)
+	}
+	return seatIDs
+}
+
+// anyAdmitted tells whether a seat of the booking ever got in.
+func anyAdmitted(items []models.BookingItem) bool {
+	for _, item := range items {
+		if item.AdmittedAt != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// admitSeats lets seats of a locked booking in at at, for a showtime
+// starting at start, and returns the seats that got in. The booking
+// becomes used with its first admission. Bookings without items, and
+// bookings used before seats were admitted one by one, are admitted as a
+// whole, once.
+func admitSeats(tx *gorm.DB, booking *models.Booking, seatIDs []uint, start, at time.Time, actor *models.User, reason string) ([]uint, error) {
+	if err := checkBookingStatus(booking.Status); err != nil {
+		return nil, err
+	}
+
+	var items []models.BookingItem
+	if err := tx.Where("booking_id = ?", booking.ID).Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	var admitted []uint
+	if len(items) == 0 || (booking.Status == models.BookingUsed && !anyAdmitted(items)) {
+		if booking.Status == models.BookingUsed {
+			return nil, &AdmissionError{Reason: models.RejectAlreadyUsed, Message: "ticket was already admitted"}
+		}
+		if err := checkAdmissionTime(start, at); err != nil {
+			return nil, err
+		}
+		admitted = bookingSeatIDs(*booking)
+	} else {
+		picked, err := planEntry(items, seatIDs, start, at)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range picked {
+			enterSeat(&items[i], at)
+			if err := tx.Model(&items[i]).Select(admittedColumns).Updates(&items[i]).Error; err != nil {
+				return nil, err
+			}
+			admitted = append(admitted, items[i].SeatID)
+		}
+	}
+	booking.Items = items
+
+	if booking.Status == models.BookingActive {
+		if err := transitionBooking(tx, booking, models.BookingUsed, actor, reason); err != nil {
+			return nil, err
+		}
+	}
+	return admitted, nil
+}
+
+// exitSeats scans seats of a locked booking out at at and returns the
+// seats that left.
+func exitSeats(tx *gorm.DB, booking *models.Booking, seatIDs []uint, at time.Time) ([]uint, error) {
+	var items []models.BookingItem
+	if err := tx.Where("booking_id = ?", booking.ID).Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	picked, err := planExit(items, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+	left := make([]uint, 0, len(picked))
+	for _, i := range picked {
+		items[i].ExitedAt = &at
+		if err := tx.Model(&items[i]).Select(admittedColumns).Updates(&items[i]).Error; err != nil {
+			return nil, err
+		}
+		left = append(left, items[i].SeatID)
+	}
+	booking.Items = items
+	return left, nil
+}
+
+// scanAttempt starts the record of an online scan by actor.
+func scanAttempt(req models.ValidateQRRequest, direction string, at time.Time, actor *models.User) models.ScanEvent {
+	event := models.ScanEvent{
+		StudioID:  req.StudioID,
+		DeviceID:  req.DeviceID,
+		Source:    models.ScanOnline,
+		Direction: direction,
+		ScannedAt: at,
+	}
+	if actor != nil {
+		event.StaffID = &actor.ID
+		event.StaffRole = actor.Role
+	}
+	return event
+}
+
+var refusedScanLog func(event *models.ScanEvent) error
+
+// UseRefusedScanLog sets where refused online scans are logged. Nil logs
+// them to the database.
+func UseRefusedScanLog(record func(event *models.ScanEvent) error) {
+	refusedScanLog = record
+}
+
+// recordRefusedScan logs an online scan that let nobody through. Tickets
+// that are not genuine are invalid; other refusals are rejected. Failing to
+// log does not change the answer the scanner gets, but is logged.
+func recordRefusedScan(event models.ScanEvent, refusal error) {
+	var refused *AdmissionError
+	if !errors.As(refusal, &refused) {
+		return
+	}
+	event.Result = models.ScanRejected
+	switch refused.Reason {
+	case models.RejectMalformedTicket, models.RejectUnknownKey, models.RejectBadSignature:
+		event.Result = models.ScanInvalid
+	}
+	event.Reason = refused.Reason
+
+	var err error
+	if refusedScanLog != nil {
+		err = refusedScanLog(&event)
+	} else {
+		err = database.DB.Create(&event).Error
+	}
+	if err != nil {
+		log.Printf("Failed to log refused scan of booking %q from device %q: %v", event.BookingCode, event.DeviceID, err)
+	}
+}
+
+// RecordExit scans seats of a booking out at the entrance so they can get
+// in again under the exit_scan re-entry policy. Without seats every seat
+// inside leaves. Every attempt is logged with the staff account of actor.
+func RecordExit(req models.ValidateQRRequest, actor *models.User) (*models.Booking, []uint, error) {
+	if req.StudioID == 0 {
+		return nil, nil, ErrStudioRequired
+	}
+	scannedAt := time.Now()
+	event := scanAttempt(req, models.ScanExit, scannedAt, actor)
+
+	ticket, err := utils.VerifyTicket(req.Ticket)
+	if err != nil {
+		err = ticketRejection(err)
+		recordRefusedScan(event, err)
+		return nil, nil, err
+	}
+	event.BookingCode = ticket.BookingCode
+	event.ShowtimeID = ticket.ShowtimeID
+	if err := checkStudio(*ticket, req.StudioID); err != nil {
+		recordRefusedScan(event, err)
+		return nil, nil, err
+	}
+
+	var booking models.Booking
+	var left []uint
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_code = ?", ticket.BookingCode).
+			First(&booking).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &AdmissionError{Reason: models.RejectNotFound, Message: "invalid or used ticket"}
+		}
+		if err != nil {
+			return err
+		}
+		event.BookingID = &booking.ID
+		event.ShowtimeID = booking.ShowtimeID
+
+		left, err = exitSeats(tx, &booking, req.SeatIDs, scannedAt)
+		if err != nil {
+			return err
+		}
+		event.SeatIDs = toInt64Array(left)
+		event.Result = models.ScanExited
+		return tx.Create(&event).Error
+	})
+	if err != nil {
+		recordRefusedScan(event, err)
+		return nil, nil, err
+	}
+	return &booking, left, nil
+}
+
+// GetBookingScans shows staff how a booking's seats got in: each seat with
+// its admission times and every scan of the ticket, oldest first.
+func GetBookingScans(id uint) (*models.BookingScans, error) {
+	var booking models.Booking
+	err := database.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Where("cancelled_at IS NULL").Order("id")
+	}).First(&booking, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+
+	scans := []models.ScanEvent{}
+	err = database.DB.Where("booking_id = ?", booking.ID).Order("scanned_at, id").Find(&scans).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookingScans(booking, scans), nil
+}
+
+// bookingScans counts the seats of a booking that got in and are inside.
+func bookingScans(booking models.Booking, scans []models.ScanEvent) *models.BookingScans {
+	result := &models.BookingScans{
+		BookingID:   booking.ID,
+		BookingCode: booking.BookingCode,
+		Status:      booking.Status,
+		Seats:       []models.BookingItem{},
+		Scans:       scans,
+	}
+	for _, item := range booking.Items {
+		if item.CancelledAt != nil {
+			continue
+		}
+		result.Seats = append(result.Seats, item)
+		result.SeatCount++
+		if item.AdmittedAt != nil {
+			result.AdmittedCount++
+		}
+		if item.Inside() {
+			result.InsideCount++
+		}
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"booking-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSeatEntry(t *testing.T) {
+	start := time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC)
+	entered := start.Add(-10 * time.Minute)
+	exited := start.Add(20 * time.Minute)
+
+	fresh := models.BookingItem{SeatID: 1, SeatNumber: "A1"}
+	inside := models.BookingItem{SeatID: 1, SeatNumber: "A1", AdmittedAt: &entered, LastEntryAt: &entered}
+	outside := models.BookingItem{SeatID: 1, SeatNumber: "A1", AdmittedAt: &entered, LastEntryAt: &entered, ExitedAt: &exited}
+	cancelled := models.BookingItem{SeatID: 1, SeatNumber: "A1", CancelledAt: &entered}
+	reentered := outside
+	reentered.Reentries = 2
+
+	tests := []struct {
+		name   string
+		item   models.BookingItem
+		at     time.Time
+		policy string
+		limit  string
+		want   string
+	}{
+		{"first entry", fresh, start, "", "", ""},
+		{"first entry too late", fresh, start.Add(time.Hour), models.ReentryExitScan, "", models.RejectTooLate},
+		{"cancelled seat", cancelled, start, "", "", models.RejectSeatNotBooked},
+		{"again without re-entry", outside, start.Add(time.Hour), "", "", models.RejectAlreadyUsed},
+		{"inside without re-entry", inside, start, "", "", models.RejectAlreadyUsed},
+		{"inside with exit scans", inside, start, models.ReentryExitScan, "", models.RejectAlreadyInside},
+		{"back after an exit scan", outside, start.Add(time.Hour), models.ReentryExitScan, "", ""},
+		{"inside with free re-entry", inside, start.Add(time.Hour), models.ReentryFree, "", ""},
+		{"past the limit", reentered, start.Add(time.Hour), models.ReentryExitScan, "2", models.RejectReentryLimit},
+		{"under the limit", reentered, start.Add(time.Hour), models.ReentryExitScan, "3", ""},
+		{"after re-entry closes", outside, start.Add(5 * time.Hour), models.ReentryExitScan, "", models.RejectReentryClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REENTRY_POLICY", tt.policy)
+			t.Setenv("REENTRY_LIMIT", tt.limit)
+			assert.Equal(t, tt.want, rejectionReason(t, checkSeatEntry(tt.item, start, tt.at)))
+		})
+	}
+}
+
+func TestPlanEntry(t *testing.T) {
+	start := time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC)
+	entered := start.Add(-10 * time.Minute)
+	exited := start.Add(20 * time.Minute)
+	at := start.Add(25 * time.Minute)
+
+	items := []models.BookingItem{
+		{SeatID: 1, SeatNumber: "A1", AdmittedAt: &entered},
+		{SeatID: 2, SeatNumber: "A2", AdmittedAt: &entered, ExitedAt: &exited},
+		{SeatID: 3, SeatNumber: "A3"},
+		{SeatID: 4, SeatNumber: "A4", CancelledAt: &entered},
+	}
+	allInside := []models.BookingItem{
+		{SeatID: 1, SeatNumber: "A1", AdmittedAt: &entered},
+		{SeatID: 2, SeatNumber: "A2", AdmittedAt: &entered},
+	}
+
+	tests := []struct {
+		name    string
+		items   []models.BookingItem
+		seatIDs []uint
+		policy  string
+		want    []int
+		reason  string
+	}{
+		{"seats still outside", items, nil, "", []int{2}, ""},
+		{"outside and back from an exit", items, nil, models.ReentryExitScan, []int{1, 2}, ""},
+		{"named seat", items, []uint{3}, "", []int{2}, ""},
+		{"named seats all or nothing", items, []uint{2, 3}, "", nil, models.RejectAlreadyUsed},
+		{"named seats coming back", items, []uint{2, 3}, models.ReentryExitScan, []int{1, 2}, ""},
+		{"seat not on the booking", items, []uint{9}, "", nil, models.RejectSeatNotBooked},
+		{"cancelled seat", items, []uint{4}, "", nil, models.RejectSeatNotBooked},
+		{"everyone inside", allInside, nil, "", nil, models.RejectAlreadyUsed},
+		{"everyone inside with exit scans", allInside, nil, models.ReentryExitScan, nil, models.RejectAlreadyInside},
+		{"everyone inside with free re-entry", allInside, nil, models.ReentryFree, []int{0, 1}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REENTRY_POLICY", tt.policy)
+			picked, err := planEntry(tt.items, tt.seatIDs, start, at)
+			assert.Equal(t, tt.reason, rejectionReason(t, err))
+			assert.Equal(t, tt.want, picked)
+		})
+	}
+}
+
+func TestPlanExit(t *testing.T) {
+	entered := time.Date(2024, 5, 1, 18, 50, 0, 0, time.UTC)
+	items := []models.BookingItem{
+		{SeatID: 1, SeatNumber: "A1", AdmittedAt: &entered},
+		{SeatID: 2, SeatNumber: "A2"},
+		{SeatID: 3, SeatNumber: "A3", AdmittedAt: &entered},
+	}
+
+	picked, err := planExit(items, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, picked)
+
+	picked, err = planExit(items, []uint{3})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, picked)
+
+	_, err = planExit(items, []uint{1, 2})
+	assert.Equal(t, models.RejectNotInside, rejectionReason(t, err))
+
+	_, err = planExit(items[1:2], nil)
+	assert.Equal(t, models.RejectNotInside, rejectionReason(t, err))
+}
+
+func TestEnterSeat(t *testing.T) {
+	first := time.Date(2024, 5, 1, 18, 50, 0, 0, time.UTC)
+	again := first.Add(time.Hour)
+	item := models.BookingItem{SeatID: 1}
+
+	enterSeat(&item, first)
+	assert.Equal(t, first, *item.AdmittedAt)
+	assert.Equal(t, 0, item.Reentries)
+
+	item.ExitedAt = &first
+	enterSeat(&item, again)
+	assert.Equal(t, first, *item.AdmittedAt)
+	assert.Equal(t, again, *item.LastEntryAt)
+	assert.Nil(t, item.ExitedAt)
+	assert.Equal(t, 1, item.Reentries)
+}
+
+func TestBookingScans(t *testing.T) {
+	at := time.Date(2024, 5, 1, 18, 50, 0, 0, time.UTC)
+	booking := models.Booking{
+		ID:          7,
+		BookingCode: "BOOK123",
+		Status:      models.BookingUsed,
+		Items: []models.BookingItem{
+			{SeatID: 1, AdmittedAt: &at},
+			{SeatID: 2, AdmittedAt: &at, ExitedAt: &at},
+			{SeatID: 3},
+		},
+	}
+
+	scans := bookingScans(booking, []models.ScanEvent{})
+	assert.Equal(t, 3, scans.SeatCount)
+	assert.Equal(t, 2, scans.AdmittedCount)
+	assert.Equal(t, 1, scans.InsideCount)
+	assert.Len(t, scans.Seats, 3)
+}
+
+func TestRecordRefusedScanLogsFailures(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	UseRefusedScanLog(func(event *models.ScanEvent) error {
+		assert.Equal(t, models.ScanRejected, event.Result)
+		assert.Equal(t, models.RejectWrongStudio, event.Reason)
+		return errors.New("database is down")
+	})
+	defer UseRefusedScanLog(nil)
+
+	recordRefusedScan(models.ScanEvent{BookingCode: "BOOK123", DeviceID: "door-1"}, &AdmissionError{Reason: models.RejectWrongStudio})
+	assert.Contains(t, output.String(), `booking "BOOK123" from device "door-1": database is down`)
+}
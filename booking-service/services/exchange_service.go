@@ -0,0 +1,243 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrExchangeSeatCount      = errors.New("seatIds and newSeatIds must name the same number of seats, at least one")
+	ErrDuplicateExchange      = errors.New("seatIds and newSeatIds may not list a seat twice")
+	ErrExchangeSeatKept       = errors.New("newSeatIds may not name seats the booking keeps")
+	ErrExchangeCostsMore      = errors.New("the new seats cost more than the old ones; cancel and book again instead")
+	ErrBookingNotExchangeable = errors.New("only active bookings can change seats")
+	ErrBookingChanged         = errors.New("the booking changed while its seats were being exchanged; try again")
+)
+
+// seatExchange is a checked request to move seats of a booking.
+type seatExchange struct {
+	// categories maps each new seat to the customer category of the seat
+	// it replaces.
+	categories map[uint]string
+	// hold lists the new seats the booking does not own yet and release
+	// the old seats it gives up for good. Seats that appear on both sides
+	// stay sold to the booking.
+	hold    []uint
+	release []uint
+	// kept lists every seat of the booking after the exchange.
+	kept []uint
+}
+
+// planSeatExchange pairs the seats a booking gives up with the seats it
+// gets, in order, and works out which seats to hold and release.
+func planSeatExchange(booked []int64, items []models.BookingItem, from, to []uint) (*seatExchange, error) {
+	if len(from) == 0 || len(from) != len(to) {
+		return nil, ErrExchangeSeatCount
+	}
+
+	owned := make(map[uint]bool, len(booked))
+	for _, id := range booked {
+		owned[uint(id)] = true
+	}
+	categoryOf := make(map[uint]string, len(items))
+	for _, item := range items {
+		categoryOf[item.SeatID] = item.CustomerCategory
+	}
+
+	leaving := make(map[uint]bool, len(from))
+	for _, id := range from {
+		if leaving[id] {
+			return nil, ErrDuplicateExchange
+		}
+		if !owned[id] {
+			return nil, ErrSeatsNotInBooking
+		}
+		leaving[id] = true
+	}
+
+	plan := &seatExchange{categories: make(map[uint]string, len(to))}
+	arriving := make(map[uint]bool, len(to))
+	for i, id := range to {
+		if arriving[id] {
+			return nil, ErrDuplicateExchange
+		}
+		if owned[id] && !leaving[id] {
+			return nil, ErrExchangeSeatKept
+		}
+		arriving[id] = true
+
+		category := categoryOf[from[i]]
+		if category == "" {
+			category = models.CustomerAdult
+		}
+		plan.categories[id] = category
+		if !owned[id] {
+			plan.hold = append(plan.hold, id)
+		}
+	}
+
+	for _, id := range booked {
+		seat := uint(id)
+		if !leaving[seat] {
+			plan.kept = append(plan.kept, seat)
+		}
+	}
+	plan.kept = append(plan.kept, to...)
+	for _, id := range from {
+		if !arriving[id] {
+			plan.release = append(plan.release, id)
+		}
+	}
+	return plan, nil
+}
+
+// ExchangeSeats moves seats of an active booking to other seats of the same
+// showtime. Cinema service is asked before the booking is locked: the new
+// seats are held and sold first, and the booking is then updated in a
+// short transaction. If the booking changed meanwhile or cannot be saved,
+// the new seats are released again and the booking stays as it was.
+// Exchanges may not cost more than the seats they replace; a cheaper
+// exchange claims the difference on the payment and refunds it after the
+// commit. The booking gets a new code and QR code. It also returns the
+// single seats the new seats strand in studios that only warn about them.
+func ExchangeSeats(id uint, actor models.User, from, to []uint) (*models.Booking, []utils.SeatGap, error) {
+	var booking models.Booking
+	if err := database.DB.First(&booking, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrBookingNotFound
+		}
+		return nil, nil, err
+	}
+	if _, err := changeWindow(booking, actor, time.Now()); err != nil {
+		return nil, nil, err
+	}
+	if booking.Status != models.BookingActive {
+		return nil, nil, ErrBookingNotExchangeable
+	}
+
+	var items []models.BookingItem
+	if err := database.DB.Where("booking_id = ? AND cancelled_at IS NULL", booking.ID).Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	plan, err := planSeatExchange(booking.SeatIDs, items, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var holdToken string
+	var gapWarnings []utils.SeatGap
+	if len(plan.hold) > 0 {
+		holdToken, gapWarnings, err = utils.HoldSeats(booking.StudioID, booking.ShowtimeID, plan.hold)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	_, newItems, _, err := quoteTickets(booking.StudioID, booking.ShowtimeID, to, plan.categories)
+	if err == nil && holdToken != "" {
+		err = utils.ConfirmHold(holdToken)
+	}
+	if err != nil {
+		if holdToken != "" {
+			utils.ReleaseHold(holdToken)
+		}
+		return nil, nil, err
+	}
+
+	var payment *models.Payment
+	var refund int64
+	planned := booking.SeatIDs
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			return err
+		}
+		if booking.Status != models.BookingActive {
+			return ErrBookingNotExchangeable
+		}
+		if !slices.Equal(booking.SeatIDs, planned) {
+			return ErrBookingChanged
+		}
+
+		leaving := make(map[uint]bool, len(from))
+		for _, seatID := range from {
+			leaving[seatID] = true
+		}
+		keptItems := newItems
+		for _, item := range items {
+			if !leaving[item.SeatID] {
+				keptItems = append(keptItems, item)
+			}
+		}
+		discount, total, err := repriceBooking(tx, booking, keptItems)
+		if err != nil {
+			return err
+		}
+		if total > booking.TotalAmount {
+			return ErrExchangeCostsMore
+		}
+		refund = booking.TotalAmount - total
+
+		bookingCode := uuid.New().String()
+		qrCode, err := utils.GenerateQRCode(bookingCode, booking.StudioID, booking.ShowtimeID, plan.kept, booking.UserID, booking.UserName)
+		if err != nil {
+			return fmt.Errorf("failed to generate QR code")
+		}
+
+		err = tx.Model(&models.BookingItem{}).
+			Where("booking_id = ? AND seat_id IN ? AND cancelled_at IS NULL", booking.ID, from).
+			Update("cancelled_at", now).Error
+		if err != nil {
+			return err
+		}
+		for i := range newItems {
+			newItems[i].BookingID = booking.ID
+		}
+		if err := tx.Create(&newItems).Error; err != nil {
+			return err
+		}
+
+		booking.BookingCode = bookingCode
+		booking.QRCode = qrCode
+		booking.SeatIDs = toInt64Array(plan.kept)
+		booking.Discount = discount
+		booking.TotalAmount = total
+		booking.RefundAmount += refund
+		err = tx.Model(&booking).
+			Select("booking_code", "qr_code", "seat_ids", "discount", "total_amount", "refund_amount").
+			Updates(&booking).Error
+		if err != nil {
+			return err
+		}
+
+		payment, err = claimRefund(tx, booking.ID, refund)
+		return err
+	})
+	if err != nil {
+		if holdToken != "" {
+			utils.ReleaseSeats(booking.ShowtimeID, plan.hold)
+		}
+		return nil, nil, err
+	}
+
+	if payment != nil {
+		settleRefund(payment, refund)
+	}
+	if len(plan.release) > 0 {
+		utils.ReleaseSeats(booking.ShowtimeID, plan.release)
+	}
+
+	if err := database.DB.Where("booking_id = ?", booking.ID).Find(&booking.Items).Error; err != nil {
+		return nil, nil, err
+	}
+	return &booking, gapWarnings, nil
+}
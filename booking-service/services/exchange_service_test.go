@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"booking-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanSeatExchange(t *testing.T) {
+	booked := []int64{4, 5, 6}
+	items := []models.BookingItem{
+		{SeatID: 4, CustomerCategory: models.CustomerAdult},
+		{SeatID: 5, CustomerCategory: models.CustomerChild},
+		{SeatID: 6, CustomerCategory: models.CustomerSenior},
+	}
+
+	tests := []struct {
+		name    string
+		from    []uint
+		to      []uint
+		want    *seatExchange
+		wantErr error
+	}{
+		{
+			name: "move one seat",
+			from: []uint{5},
+			to:   []uint{9},
+			want: &seatExchange{
+				categories: map[uint]string{9: models.CustomerChild},
+				hold:       []uint{9},
+				release:    []uint{5},
+				kept:       []uint{4, 6, 9},
+			},
+		},
+		{
+			name: "swap seats within the booking",
+			from: []uint{4, 5},
+			to:   []uint{5, 10},
+			want: &seatExchange{
+				categories: map[uint]string{5: models.CustomerAdult, 10: models.CustomerChild},
+				hold:       []uint{10},
+				release:    []uint{4},
+				kept:       []uint{6, 5, 10},
+			},
+		},
+		{name: "nothing to exchange", wantErr: ErrExchangeSeatCount},
+		{name: "uneven lists", from: []uint{4, 5}, to: []uint{9}, wantErr: ErrExchangeSeatCount},
+		{name: "seat from elsewhere", from: []uint{7}, to: []uint{9}, wantErr: ErrSeatsNotInBooking},
+		{name: "seat given up twice", from: []uint{4, 4}, to: []uint{9, 10}, wantErr: ErrDuplicateExchange},
+		{name: "seat taken twice", from: []uint{4, 5}, to: []uint{9, 9}, wantErr: ErrDuplicateExchange},
+		{name: "seat already kept", from: []uint{4}, to: []uint{6}, wantErr: ErrExchangeSeatKept},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planSeatExchange(booked, items, tt.from, tt.to)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/payments"
+	"booking-service/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentsUnavailable = errors.New("payments are not available")
+	ErrPaymentNotFound     = errors.New("payment not found")
+	ErrPaymentCapturing    = errors.New("the payment is being taken; try again shortly")
+)
+
+var paymentProvider payments.Provider
+
+// UsePaymentProvider sets the provider online bookings are paid through.
+func UsePaymentProvider(provider payments.Provider) {
+	paymentProvider = provider
+}
+
+// startPayment authorizes the booking total with the payment provider and
+// records the payment. The booking waits for the provider's webhook.
+func startPayment(tx *gorm.DB, booking *models.Booking) error {
+	if paymentProvider == nil {
+		return ErrPaymentsUnavailable
+	}
+
+	auth, err := paymentProvider.Authorize(payments.AuthorizeRequest{
+		BookingCode: booking.BookingCode,
+		Amount:      booking.TotalAmount,
+		Currency:    booking.Currency,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start payment")
+	}
+
+	payment := models.Payment{
+		BookingID:    booking.ID,
+		Provider:     paymentProvider.Name(),
+		PaymentID:    auth.PaymentID,
+		ClientSecret: auth.ClientSecret,
+		Amount:       booking.TotalAmount,
+		Currency:     booking.Currency,
+		Status:       models.PaymentAuthorized,
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return fmt.Errorf("failed to start payment")
+	}
+	booking.Payments = []models.Payment{payment}
+	return nil
+}
+
+// HandlePaymentWebhook verifies a webhook from the payment provider and
+// moves the booking it is about along. Webhooks may be delivered more than
+// once; repeats of an event that was already handled change nothing.
+func HandlePaymentWebhook(header http.Header, body []byte) error {
+	if paymentProvider == nil {
+		return ErrPaymentsUnavailable
+	}
+
+	event, err := paymentProvider.VerifyWebhook(header, body)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case payments.EventPaymentSucceeded:
+		return completePayment(event.PaymentID)
+	case payments.EventPaymentFailed:
+		return failPayment(event.PaymentID)
+	}
+	return nil
+}
+
+// lockPayment loads a payment and locks its booking for the rest of the
+// transaction.
+func lockPayment(tx *gorm.DB, paymentID string) (*models.Payment, *models.Booking, error) {
+	var payment models.Payment
+	err := tx.Where("provider = ? AND payment_id = ?", paymentProvider.Name(), paymentID).First(&payment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrPaymentNotFound
+		}
+		return nil, nil, err
+	}
+
+	var booking models.Booking
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, payment.BookingID).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return &payment, &booking, nil
+}
+
+// completePayment takes a successful payment and then confirms the
+// booking's seat hold. When cinema service says the hold is gone, usually
+// because it expired while the customer was paying, the payment is
+// refunded and the booking fails. Any other failure is returned so the
+// webhook is delivered again; bookings left paid are finished on a retry
+// or by the expiry sweeper.
+func completePayment(paymentID string) error {
+	payment, booking, err := claimCapture(paymentID)
+	if err != nil || payment == nil {
+		return err
+	}
+	if payment.Status == models.PaymentCapturing {
+		if err := capturePayment(payment, booking); err != nil {
+			return err
+		}
+	}
+
+	if err := utils.ConfirmHold(booking.HoldToken); err != nil {
+		if !errors.Is(err, utils.ErrHoldGone) {
+			return err
+		}
+		log.Printf("Refunding booking %s: %v", booking.BookingCode, err)
+		return refundFailedBooking(payment, booking)
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, booking.ID).Error; err != nil {
+			return err
+		}
+		// A concurrent retry may have finished the booking already.
+		if booking.Status != models.BookingPaid {
+			return nil
+		}
+		return transitionBooking(tx, booking, models.BookingActive, nil, "")
+	})
+}
+
+// claimCapture marks the payment of a booking that is still waiting for it
+// as capturing, and commits that before any money is taken. Payments left
+// capturing by an earlier attempt are claimed again, and bookings left paid
+// are returned with their captured payment so they can be finished. It
+// returns no payment when there is nothing left to do.
+func claimCapture(paymentID string) (*models.Payment, *models.Booking, error) {
+	var payment *models.Payment
+	var booking *models.Booking
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		locked, lockedBooking, err := lockPayment(tx, paymentID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case lockedBooking.Status == models.BookingPaid && locked.Status == models.PaymentCaptured:
+		case lockedBooking.Status != models.BookingPendingPayment:
+			return nil
+		case locked.Status == models.PaymentAuthorized:
+			if err := tx.Model(locked).Update("status", models.PaymentCapturing).Error; err != nil {
+				return err
+			}
+		case locked.Status != models.PaymentCapturing:
+			return nil
+		}
+		payment, booking = locked, lockedBooking
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return payment, booking, nil
+}
+
+// saveCapture records a captured payment and marks its booking paid. Tests
+// replace it to make saving fail after the money was taken.
+var saveCapture = recordCapture
+
+// capturePayment has the provider take a claimed payment and records it.
+// If recording fails the payment stays capturing, and the error makes the
+// provider deliver its webhook again; the expiry sweeper retries it too.
+// Capturing again takes nothing more, so the retry only saves it.
+func capturePayment(payment *models.Payment, booking *models.Booking) error {
+	if err := paymentProvider.Capture(payment.PaymentID, payment.Amount); err != nil {
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+	if err := saveCapture(payment, booking); err != nil {
+		return fmt.Errorf("payment was captured but not recorded: %w", err)
+	}
+	return nil
+}
+
+func recordCapture(payment *models.Payment, booking *models.Booking) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(payment).Update("status", models.PaymentCaptured).Error; err != nil {
+			return err
+		}
+		return transitionBooking(tx, booking, models.BookingPaid, nil, "")
+	})
+}
+
+// refundFailedBooking pays back a captured payment whose seats could not be
+// sold and marks the booking refunded. If the provider refuses, the booking
+// stays paid and is tried again.
+func refundFailedBooking(payment *models.Payment, booking *models.Booking) error {
+	if err := paymentProvider.Refund(payment.PaymentID, payment.Amount); err != nil {
+		return fmt.Errorf("failed to refund payment: %w", err)
+	}
+	utils.ReleaseHold(booking.HoldToken)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(payment).Updates(map[string]interface{}{
+			"status":          models.PaymentRefunded,
+			"refunded_amount": payment.Amount,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := transitionBooking(tx, booking, models.BookingRefunded, nil, "seats could not be sold"); err != nil {
+			return err
+		}
+		return releasePromoCode(tx, booking.ID)
+	})
+	if err != nil {
+		logUnsavedRefund(payment, payment.Amount, err)
+	}
+	return err
+}
+
+// failPayment gives up on a booking whose payment did not go through and
+// releases its seats.
+func failPayment(paymentID string) error {
+	var booking *models.Booking
+	released := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		payment, locked, err := lockPayment(tx, paymentID)
+		if err != nil {
+			return err
+		}
+		booking = locked
+		if booking.Status != models.BookingPendingPayment {
+			return nil
+		}
+
+		if err := tx.Model(payment).Update("status", models.PaymentFailed).Error; err != nil {
+			return err
+		}
+		if err := transitionBooking(tx, booking, models.BookingPaymentFailed, nil, ""); err != nil {
+			return err
+		}
+		if err := releasePromoCode(tx, booking.ID); err != nil {
+			return err
+		}
+		released = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if released {
+		utils.ReleaseHold(booking.HoldToken)
+	}
+	return nil
+}
+
+// paymentTimeout is how long an online booking waits for its payment. It
+// matches the seat hold, after which cinema service sells the seats again.
+func paymentTimeout() time.Duration {
+	if v := os.Getenv("SEAT_HOLD_TTL_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return 10 * time.Minute
+}
+
+// voidPayments voids the authorized payments of a locked booking that will
+// not be paid and marks them voided. The provider is asked first, so a
+// booking whose payment could not be voided keeps its status. Payments
+// that are being captured cannot be voided.
+func voidPayments(tx *gorm.DB, bookingID uint) error {
+	var open []models.Payment
+	err := tx.Where("booking_id = ? AND status IN ?", bookingID, []string{models.PaymentAuthorized, models.PaymentCapturing}).
+		Find(&open).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range open {
+		if open[i].Status == models.PaymentCapturing {
+			return ErrPaymentCapturing
+		}
+		if paymentProvider == nil {
+			return ErrPaymentsUnavailable
+		}
+		if err := paymentProvider.Void(open[i].PaymentID); err != nil {
+			return fmt.Errorf("failed to void payment: %w", err)
+		}
+		if err := tx.Model(&open[i]).Update("status", models.PaymentVoided).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpireUnpaidBookings expires online bookings whose payment did not arrive
+// in time, voids their payments and releases their seat holds. Payments
+// that were captured but never recorded, and paid bookings whose seats
+// were never confirmed, are completed instead. Bookings that cannot be
+// expired now are logged and tried again on the next run. It returns how
+// many bookings expired.
+func ExpireUnpaidBookings() (int, error) {
+	cutoff := time.Now().Add(-paymentTimeout())
+
+	var unfinished []string
+	err := database.DB.Model(&models.Payment{}).
+		Joins("JOIN bookings ON bookings.id = payments.booking_id").
+		Where("bookings.created_at < ?", cutoff).
+		Where("(bookings.status = ? AND payments.status = ?) OR (bookings.status = ? AND payments.status = ?)",
+			models.BookingPendingPayment, models.PaymentCapturing, models.BookingPaid, models.PaymentCaptured).
+		Pluck("payments.payment_id", &unfinished).Error
+	if err != nil {
+		return 0, err
+	}
+	for _, paymentID := range unfinished {
+		if err := completePayment(paymentID); err != nil {
+			log.Printf("Failed to complete payment %s: %v", paymentID, err)
+		}
+	}
+
+	var ids []uint
+	err = database.DB.Model(&models.Booking{}).
+		Where("status = ? AND created_at < ?", models.BookingPendingPayment, cutoff).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		var booking models.Booking
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+				return err
+			}
+			if booking.Status != models.BookingPendingPayment {
+				return &TransitionError{From: booking.Status, To: models.BookingExpired}
+			}
+			if err := voidPayments(tx, booking.ID); err != nil {
+				return err
+			}
+			if err := transitionBooking(tx, &booking, models.BookingExpired, nil, "payment did not arrive in time"); err != nil {
+				return err
+			}
+			return releasePromoCode(tx, booking.ID)
+		})
+		// Bookings paid or cancelled since they were listed, and bookings
+		// whose capture still has to be recorded, are left alone.
+		if errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrPaymentCapturing) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to expire booking %d: %v", id, err)
+			continue
+		}
+		utils.ReleaseHold(booking.HoldToken)
+		expired++
+	}
+	return expired, nil
+}
+
+// StartPaymentExpirySweeper expires unpaid bookings in the background every
+// interval.
+func StartPaymentExpirySweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			expired, err := ExpireUnpaidBookings()
+			if err != nil {
+				log.Println("Failed to expire unpaid bookings:", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Expired %d unpaid bookings", expired)
+			}
+		}
+	}()
+}
+
+// SimulatePayment makes the fake payment provider report that a payment
+// succeeded or failed, as a customer finishing the payment would.
+func SimulatePayment(paymentID string, succeeded bool) error {
+	fake, ok := paymentProvider.(*payments.FakeProvider)
+	if !ok {
+		return ErrPaymentsUnavailable
+	}
+	header, body := fake.Webhook(paymentID, succeeded)
+	return HandlePaymentWebhook(header, body)
+}
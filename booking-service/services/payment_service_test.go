@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"booking-service/models"
+	"booking-service/payments"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapturePaymentSaveFails(t *testing.T) {
+	provider := payments.NewFakeProvider("secret")
+	UsePaymentProvider(provider)
+	defer UsePaymentProvider(nil)
+
+	auth, err := provider.Authorize(payments.AuthorizeRequest{BookingCode: "b1", Amount: 2400, Currency: "USD"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	payment := &models.Payment{PaymentID: auth.PaymentID, Amount: 2400, Status: models.PaymentCapturing}
+	booking := &models.Booking{BookingCode: "b1", Status: models.BookingPendingPayment}
+
+	commitFailed := errors.New("commit failed")
+	saves := 0
+	saveCapture = func(payment *models.Payment, booking *models.Booking) error {
+		saves++
+		if saves == 1 {
+			return commitFailed
+		}
+		payment.Status = models.PaymentCaptured
+		booking.Status = models.BookingPaid
+		return nil
+	}
+	defer func() { saveCapture = recordCapture }()
+
+	// The money is taken but the booking is not paid; the payment is left
+	// capturing for a retry.
+	err = capturePayment(payment, booking)
+	assert.ErrorIs(t, err, commitFailed)
+	assert.Equal(t, models.PaymentCapturing, payment.Status)
+	assert.Equal(t, models.BookingPendingPayment, booking.Status)
+	captured, _ := provider.Captured(auth.PaymentID)
+	assert.Equal(t, int64(2400), captured)
+
+	// Retrying captures nothing more and records the payment.
+	assert.NoError(t, capturePayment(payment, booking))
+	assert.Equal(t, 2, saves)
+	assert.Equal(t, models.PaymentCaptured, payment.Status)
+	assert.Equal(t, models.BookingPaid, booking.Status)
+	captured, refunded := provider.Captured(auth.PaymentID)
+	assert.Equal(t, int64(2400), captured)
+	assert.Equal(t, int64(0), refunded)
+}
+
+func TestCapturePaymentProviderFails(t *testing.T) {
+	UsePaymentProvider(payments.NewFakeProvider("secret"))
+	defer UsePaymentProvider(nil)
+
+	saveCapture = func(*models.Payment, *models.Booking) error {
+		t.Fatal("a payment that was not captured must not be recorded")
+		return nil
+	}
+	defer func() { saveCapture = recordCapture }()
+
+	payment := &models.Payment{PaymentID: "fake_missing", Amount: 2400, Status: models.PaymentCapturing}
+	err := capturePayment(payment, &models.Booking{Status: models.BookingPendingPayment})
+	assert.ErrorIs(t, err, payments.ErrUnknownPayment)
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"booking-service/models"
+	"booking-service/utils"
+)
+
+var (
+	ErrInvalidCustomerCategory = errors.New("category must be one of adult, child, senior or student")
+	ErrTicketSeatNotBooked     = errors.New("tickets may only name seats listed in seatIds")
+	ErrDuplicateTicket         = errors.New("tickets lists the same seat more than once")
+)
+
+// eveningStartHour is the local hour from which weekday showtimes are
+// priced as evening shows.
+const eveningStartHour = 17
+
+// priceTable holds ticket prices in the minor unit of the currency.
+// Discounts are percentages taken off the whole ticket.
+type priceTable struct {
+	slots      map[string]int64
+	formats    map[string]int64
+	seatTypes  map[string]int64
+	categories map[string]int64
+}
+
+var ticketPrices = priceTable{
+	slots: map[string]int64{
+		models.SlotMatinee: 800,
+		models.SlotEvening: 1200,
+		models.SlotWeekend: 1400,
+	},
+	formats: map[string]int64{
+		"2D":   0,
+		"3D":   300,
+		"IMAX": 500,
+	},
+	seatTypes: map[string]int64{
+		"standard":   0,
+		"premium":    300,
+		"couch":      400,
+		"wheelchair": 0,
+		"companion":  0,
+	},
+	categories: map[string]int64{
+		models.CustomerAdult:   0,
+		models.CustomerChild:   30,
+		models.CustomerSenior:  30,
+		models.CustomerStudent: 20,
+	},
+}
+
+// priceCurrency is the currency ticket prices are quoted in.
+func priceCurrency() string {
+	if v := os.Getenv("PRICE_CURRENCY"); v != "" {
+		return strings.ToUpper(v)
+	}
+	return "USD"
+}
+
+// showtimeSlot tells which price slot a showtime starting at start falls
+// in, judged by the local time of the booking service.
+func showtimeSlot(start time.Time) string {
+	local := start.In(time.Local)
+	switch {
+	case local.Weekday() == time.Saturday || local.Weekday() == time.Sunday:
+		return models.SlotWeekend
+	case local.Hour() < eveningStartHour:
+		return models.SlotMatinee
+	default:
+		return models.SlotEvening
+	}
+}
+
+// ticketCategories maps every requested seat to the customer category it
+// is priced for, checking the tickets against the seats being booked.
+func ticketCategories(seatIDs []uint, tickets []models.TicketRequest) (map[uint]string, error) {
+	categories := make(map[uint]string, len(seatIDs))
+	for _, id := range seatIDs {
+		categories[id] = models.CustomerAdult
+	}
+
+	named := make(map[uint]bool, len(tickets))
+	for _, ticket := range tickets {
+		if _, ok := categories[ticket.SeatID]; !ok {
+			return nil, ErrTicketSeatNotBooked
+		}
+		if named[ticket.SeatID] {
+			return nil, ErrDuplicateTicket
+		}
+		named[ticket.SeatID] = true
+
+		category := strings.ToLower(strings.TrimSpace(ticket.Category))
+		if category == "" {
+			category = models.CustomerAdult
+		}
+		if _, ok := ticketPrices.categories[category]; !ok {
+			return nil, ErrInvalidCustomerCategory
+		}
+		categories[ticket.SeatID] = category
+	}
+	return categories, nil
+}
+
+// priceTickets prices one ticket per seat: the showtime slot sets the base
+// price, the format and seat type add surcharges and the customer category
+// takes a discount off the result.
+func priceTickets(showtime utils.Showtime, seats []utils.Seat, seatIDs []uint, categories map[uint]string) ([]models.BookingItem, int64, error) {
+	seatsByID := make(map[uint]utils.Seat, len(seats))
+	for _, seat := range seats {
+		seatsByID[seat.ID] = seat
+	}
+
+	slot := showtimeSlot(showtime.StartTime)
+	format := strings.ToUpper(showtime.Format)
+	if format == "" {
+		format = "2D"
+	}
+
+	var missing []uint
+	for _, id := range seatIDs {
+		if _, ok := seatsByID[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, 0, &utils.SeatRequestError{Message: "some seats do not belong to the showtime's studio", SeatIDs: missing}
+	}
+
+	items := make([]models.BookingItem, 0, len(seatIDs))
+	var total int64
+	for _, id := range seatIDs {
+		seat := seatsByID[id]
+		seatType := seat.SeatType
+		if seatType == "" {
+			seatType = "standard"
+		}
+		category := categories[id]
+
+		item := models.BookingItem{
+			SeatID:           id,
+			SeatNumber:       seat.SeatNumber,
+			SeatType:         seatType,
+			CustomerCategory: category,
+			Slot:             slot,
+			Format:           format,
+			BasePrice:        ticketPrices.slots[slot],
+			FormatSurcharge:  ticketPrices.formats[format],
+			SeatSurcharge:    ticketPrices.seatTypes[seatType],
+		}
+		subtotal := item.BasePrice + item.FormatSurcharge + item.SeatSurcharge
+		item.Discount = subtotal * ticketPrices.categories[category] / 100
+		item.Price = subtotal - item.Discount
+
+		items = append(items, item)
+		total += item.Price
+	}
+	return items, total, nil
+}
+
+// quoteTickets looks up the showtime and its seats in cinema service and
+// prices the requested seats.
+func quoteTickets(studioID, showtimeID uint, seatIDs []uint, categories map[uint]string) (*utils.Showtime, []models.BookingItem, int64, error) {
+	showtime, err := utils.GetShowtime(showtimeID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if studioID == 0 {
+		studioID = showtime.StudioID
+	}
+	seats, err := utils.GetStudioSeats(studioID, showtimeID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	items, total, err := priceTickets(*showtime, seats, seatIDs, categories)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return showtime, items, total, nil
+}
+
+// QuoteTickets prices seats for a showtime without booking them.
+func QuoteTickets(req models.PriceQuoteRequest) (*models.PriceQuote, error) {
+	if len(req.SeatIDs) == 0 {
+		return nil, &utils.SeatRequestError{Message: "seatIds must contain at least one seat"}
+	}
+	categories, err := ticketCategories(req.SeatIDs, req.Tickets)
+	if err != nil {
+		return nil, err
+	}
+
+	_, items, total, err := quoteTickets(req.StudioID, req.ShowtimeID, req.SeatIDs, categories)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PriceQuote{
+		ShowtimeID:  req.ShowtimeID,
+		Items:       items,
+		TotalAmount: total,
+		Currency:    priceCurrency(),
+	}, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"booking-service/models"
+	"booking-service/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowtimeSlot(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		want  string
+	}{
+		{"weekday afternoon", time.Date(2024, 5, 1, 14, 0, 0, 0, time.Local), models.SlotMatinee},
+		{"weekday evening", time.Date(2024, 5, 1, 17, 0, 0, 0, time.Local), models.SlotEvening},
+		{"saturday afternoon", time.Date(2024, 5, 4, 14, 0, 0, 0, time.Local), models.SlotWeekend},
+		{"sunday evening", time.Date(2024, 5, 5, 21, 0, 0, 0, time.Local), models.SlotWeekend},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, showtimeSlot(tt.start))
+		})
+	}
+}
+
+func TestTicketCategories(t *testing.T) {
+	tests := []struct {
+		name    string
+		tickets []models.TicketRequest
+		want    map[uint]string
+		wantErr error
+	}{
+		{
+			name: "adults by default",
+			want: map[uint]string{1: models.CustomerAdult, 2: models.CustomerAdult},
+		},
+		{
+			name:    "named categories",
+			tickets: []models.TicketRequest{{SeatID: 2, Category: " Child "}},
+			want:    map[uint]string{1: models.CustomerAdult, 2: models.CustomerChild},
+		},
+		{
+			name:    "unknown category",
+			tickets: []models.TicketRequest{{SeatID: 1, Category: "pensioner"}},
+			wantErr: ErrInvalidCustomerCategory,
+		},
+		{
+			name:    "seat not booked",
+			tickets: []models.TicketRequest{{SeatID: 3, Category: models.CustomerStudent}},
+			wantErr: ErrTicketSeatNotBooked,
+		},
+		{
+			name:    "seat named twice",
+			tickets: []models.TicketRequest{{SeatID: 1, Category: models.CustomerChild}, {SeatID: 1, Category: models.CustomerSenior}},
+			wantErr: ErrDuplicateTicket,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categories, err := ticketCategories([]uint{1, 2}, tt.tickets)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, categories)
+		})
+	}
+}
+
+func TestPriceTickets(t *testing.T) {
+	showtime := utils.Showtime{ID: 1, StudioID: 1, StartTime: time.Date(2024, 5, 1, 19, 0, 0, 0, time.Local), Format: "IMAX"}
+	seats := []utils.Seat{
+		{ID: 1, SeatNumber: "A1", SeatType: "standard"},
+		{ID: 2, SeatNumber: "A2", SeatType: "premium"},
+		{ID: 3, SeatNumber: "A3", SeatType: "couch"},
+	}
+	categories := map[uint]string{1: models.CustomerAdult, 2: models.CustomerChild, 3: models.CustomerStudent}
+
+	items, total, err := priceTickets(showtime, seats, []uint{1, 2, 3}, categories)
+	assert.NoError(t, err)
+	if !assert.Len(t, items, 3) {
+		return
+	}
+
+	// Evening base 1200 plus 500 for IMAX.
+	assert.Equal(t, models.BookingItem{
+		SeatID: 1, SeatNumber: "A1", SeatType: "standard", CustomerCategory: models.CustomerAdult,
+		Slot: models.SlotEvening, Format: "IMAX", BasePrice: 1200, FormatSurcharge: 500, Price: 1700,
+	}, items[0])
+	// 2000 for a premium seat, 30% off for a child.
+	assert.Equal(t, int64(300), items[1].SeatSurcharge)
+	assert.Equal(t, int64(600), items[1].Discount)
+	assert.Equal(t, int64(1400), items[1].Price)
+	// 2100 for a couch seat, 20% off for a student.
+	assert.Equal(t, int64(420), items[2].Discount)
+	assert.Equal(t, int64(1680), items[2].Price)
+
+	assert.Equal(t, int64(1700+1400+1680), total)
+}
+
+func TestPriceTicketsUnknownSeat(t *testing.T) {
+	showtime := utils.Showtime{ID: 1, StudioID: 1, StartTime: time.Date(2024, 5, 1, 14, 0, 0, 0, time.Local)}
+	seats := []utils.Seat{{ID: 1, SeatNumber: "A1"}}
+
+	_, _, err := priceTickets(showtime, seats, []uint{1, 9}, map[uint]string{1: models.CustomerAdult, 9: models.CustomerAdult})
+
+	var invalid *utils.SeatRequestError
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, []uint{9}, invalid.SeatIDs)
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/utils"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPromoCodeRequired  = errors.New("code is required")
+	ErrInvalidPromoKind   = errors.New("kind must be one of percent, fixed or buy_n_get_m")
+	ErrInvalidPromoValue  = errors.New("percent codes need a value between 1 and 100 and fixed codes a positive value")
+	ErrInvalidPromoBundle = errors.New("buy_n_get_m codes need a buyCount and freeCount of at least 1")
+	ErrInvalidPromoWindow = errors.New("endsAt must be after startsAt")
+	ErrInvalidPromoCap    = errors.New("maxUses and maxUsesPerUser cannot be negative")
+	ErrPromoCodeTaken     = errors.New("a promo code with this code already exists")
+
+	ErrPromoNotFound      = errors.New("promo code not found")
+	ErrPromoNotActive     = errors.New("promo code is not valid at this time")
+	ErrPromoNotApplicable = errors.New("promo code does not apply to this movie or studio")
+	ErrPromoFirstBooking  = errors.New("promo code is only valid on a first booking")
+	ErrPromoExhausted     = errors.New("promo code has been used up")
+	ErrPromoUserLimit     = errors.New("promo code has been used the maximum number of times by this customer")
+)
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validatePromoRequest(req models.PromoCodeRequest) error {
+	if normalizePromoCode(req.Code) == "" {
+		return ErrPromoCodeRequired
+	}
+
+	switch req.Kind {
+	case models.PromoPercent:
+		if req.Value < 1 || req.Value > 100 {
+			return ErrInvalidPromoValue
+		}
+	case models.PromoFixed:
+		if req.Value < 1 {
+			return ErrInvalidPromoValue
+		}
+	case models.PromoBuyNGetM:
+		if req.BuyCount < 1 || req.FreeCount < 1 {
+			return ErrInvalidPromoBundle
+		}
+	default:
+		return ErrInvalidPromoKind
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return ErrInvalidPromoWindow
+	}
+	if req.MaxUses < 0 || req.MaxUsesPerUser < 0 {
+		return ErrInvalidPromoCap
+	}
+	return nil
+}
+
+func toInt64Array(ids []uint) pq.Int64Array {
+	array := make(pq.Int64Array, len(ids))
+	for i, id := range ids {
+		array[i] = int64(id)
+	}
+	return array
+}
+
+// CreatePromoCode issues a new promo code. Codes are matched without regard
+// to case.
+func CreatePromoCode(req models.PromoCodeRequest) (*models.PromoCode, error) {
+	if err := validatePromoRequest(req); err != nil {
+		return nil, err
+	}
+
+	code := normalizePromoCode(req.Code)
+	var existing int64
+	if err := database.DB.Unscoped().Model(&models.PromoCode{}).Where("code = ?", code).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrPromoCodeTaken
+	}
+
+	promo := models.PromoCode{
+		Code:             code,
+		Kind:             req.Kind,
+		Value:            req.Value,
+		BuyCount:         req.BuyCount,
+		FreeCount:        req.FreeCount,
+		FirstBookingOnly: req.FirstBookingOnly,
+		StartsAt:         req.StartsAt,
+		EndsAt:           req.EndsAt,
+		MaxUses:          req.MaxUses,
+		MaxUsesPerUser:   req.MaxUsesPerUser,
+		MovieIDs:         toInt64Array(req.MovieIDs),
+		StudioIDs:        toInt64Array(req.StudioIDs),
+	}
+	if err := database.DB.Create(&promo).Error; err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func GetPromoCodes() ([]models.PromoCode, error) {
+	var promos []models.PromoCode
+	if err := database.DB.Order("created_at DESC").Find(&promos).Error; err != nil {
+		return nil, err
+	}
+	return promos, nil
+}
+
+func containsID(ids pq.Int64Array, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == int64(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPromoCode tells whether a promo code may be used for a showtime at
+// the given time, leaving the per-customer checks to the caller.
+func checkPromoCode(promo models.PromoCode, showtime utils.Showtime, now time.Time) error {
+	if promo.StartsAt != nil && now.Before(*promo.StartsAt) {
+		return ErrPromoNotActive
+	}
+	if promo.EndsAt != nil && !now.Before(*promo.EndsAt) {
+		return ErrPromoNotActive
+	}
+	if len(promo.MovieIDs) > 0 && !containsID(promo.MovieIDs, showtime.MovieID) {
+		return ErrPromoNotApplicable
+	}
+	if len(promo.StudioIDs) > 0 && !containsID(promo.StudioIDs, showtime.StudioID) {
+		return ErrPromoNotApplicable
+	}
+	if promo.MaxUses > 0 && promo.UsedCount >= promo.MaxUses {
+		return ErrPromoExhausted
+	}
+	return nil
+}
+
+// promoDiscount works out how much a promo code takes off the priced
+// tickets. The discount never exceeds the ticket total.
+func promoDiscount(promo models.PromoCode, items []models.BookingItem) int64 {
+	var total int64
+	prices := make([]int64, len(items))
+	for i, item := range items {
+		prices[i] = item.Price
+		total += item.Price
+	}
+
+	var discount int64
+	switch promo.Kind {
+	case models.PromoPercent:
+		discount = total * promo.Value / 100
+	case models.PromoFixed:
+		discount = promo.Value
+	case models.PromoBuyNGetM:
+		// The cheapest tickets of every complete bundle are free.
+		bundle := promo.BuyCount + promo.FreeCount
+		free := len(prices) / bundle * promo.FreeCount
+		sort.Slice(prices, func(a, b int) bool { return prices[a] < prices[b] })
+		for _, price := range prices[:free] {
+			discount += price
+		}
+	}
+
+	if discount > total {
+		discount = total
+	}
+	return discount
+}
+
+// customerScope narrows a query to one customer: the signed-in user for
+// online bookings and the customer email for offline ones.
+func customerScope(userID *uint, userEmail string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userID != nil {
+			return db.Where("user_id = ?", *userID)
+		}
+		return db.Where("LOWER(user_email) = LOWER(?)", userEmail)
+	}
+}
+
+// applyPromoCode locks the promo code for the rest of the transaction and
+// checks that the booking may use it, returning the code and its discount.
+// Holding the lock until the redemption is recorded keeps concurrent
+// bookings from pushing the code past its caps.
+func applyPromoCode(tx *gorm.DB, code string, booking *models.Booking, showtime utils.Showtime) (*models.PromoCode, int64, error) {
+	var promo models.PromoCode
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", normalizePromoCode(code)).
+		First(&promo).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, ErrPromoNotFound
+		}
+		return nil, 0, err
+	}
+
+	if err := checkPromoCode(promo, showtime, time.Now()); err != nil {
+		return nil, 0, err
+	}
+
+	if promo.MaxUsesPerUser > 0 {
+		var used int64
+		err := tx.Model(&models.PromoRedemption{}).
+			Scopes(customerScope(booking.UserID, booking.UserEmail)).
+			Where("promo_code_id = ?", promo.ID).
+			Count(&used).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		if used >= int64(promo.MaxUsesPerUser) {
+			return nil, 0, ErrPromoUserLimit
+		}
+	}
+
+	// Bookings that went through or are still waiting for their payment
+	// count, so a customer cannot open several unpaid first bookings at
+	// once; failed, expired or cancelled ones do not use up a first booking.
+	if promo.FirstBookingOnly {
+		var previous int64
+		err := tx.Model(&models.Booking{}).
+			Scopes(customerScope(booking.UserID, booking.UserEmail)).
+			Where("status IN ?", []models.BookingStatus{models.BookingPendingPayment, models.BookingPaid, models.BookingActive, models.BookingUsed}).
+			Count(&previous).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		if previous > 0 {
+			return nil, 0, ErrPromoFirstBooking
+		}
+	}
+
+	return &promo, promoDiscount(promo, booking.Items), nil
+}
+
+// redeemPromoCode counts a use of a promo code by a stored booking.
+func redeemPromoCode(tx *gorm.DB, promo *models.PromoCode, booking *models.Booking) error {
+	result := tx.Model(&models.PromoCode{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", promo.ID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPromoExhausted
+	}
+
+	return tx.Create(&models.PromoRedemption{
+		PromoCodeID: promo.ID,
+		BookingID:   booking.ID,
+		UserID:      booking.UserID,
+		UserEmail:   booking.UserEmail,
+		Discount:    booking.Discount,
+	}).Error
+}
+
+// releasePromoCode gives back the use of a promo code counted for a booking
+// that did not go through or was cancelled, so the code and the customer's
+// allowance can be used again.
+func releasePromoCode(tx *gorm.DB, bookingID uint) error {
+	var redemption models.PromoRedemption
+	err := tx.Where("booking_id = ?", bookingID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Delete(&redemption).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.PromoCode{}).
+		Where("id = ? AND used_count > 0", redemption.PromoCodeID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
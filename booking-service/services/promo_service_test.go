@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"booking-service/models"
+	"booking-service/utils"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePromoRequest(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		req     models.PromoCodeRequest
+		wantErr error
+	}{
+		{"percent", models.PromoCodeRequest{Code: "spring", Kind: models.PromoPercent, Value: 15}, nil},
+		{"fixed with window", models.PromoCodeRequest{Code: "FIVE", Kind: models.PromoFixed, Value: 500, StartsAt: &start, EndsAt: &end}, nil},
+		{"buy two get one", models.PromoCodeRequest{Code: "B2G1", Kind: models.PromoBuyNGetM, BuyCount: 2, FreeCount: 1}, nil},
+		{"missing code", models.PromoCodeRequest{Code: "  ", Kind: models.PromoPercent, Value: 10}, ErrPromoCodeRequired},
+		{"unknown kind", models.PromoCodeRequest{Code: "X", Kind: "bogo"}, ErrInvalidPromoKind},
+		{"percent over 100", models.PromoCodeRequest{Code: "X", Kind: models.PromoPercent, Value: 120}, ErrInvalidPromoValue},
+		{"fixed without value", models.PromoCodeRequest{Code: "X", Kind: models.PromoFixed}, ErrInvalidPromoValue},
+		{"bundle without free tickets", models.PromoCodeRequest{Code: "X", Kind: models.PromoBuyNGetM, BuyCount: 2}, ErrInvalidPromoBundle},
+		{"window ends before it starts", models.PromoCodeRequest{Code: "X", Kind: models.PromoPercent, Value: 10, StartsAt: &end, EndsAt: &start}, ErrInvalidPromoWindow},
+		{"negative cap", models.PromoCodeRequest{Code: "X", Kind: models.PromoPercent, Value: 10, MaxUsesPerUser: -1}, ErrInvalidPromoCap},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePromoRequest(tt.req)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPromoCode(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	earlier := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+	showtime := utils.Showtime{ID: 1, MovieID: 3, StudioID: 2}
+
+	tests := []struct {
+		name    string
+		promo   models.PromoCode
+		wantErr error
+	}{
+		{"unrestricted", models.PromoCode{}, nil},
+		{"inside window", models.PromoCode{StartsAt: &earlier, EndsAt: &later}, nil},
+		{"not started", models.PromoCode{StartsAt: &later}, ErrPromoNotActive},
+		{"ended", models.PromoCode{EndsAt: &now}, ErrPromoNotActive},
+		{"matching movie and studio", models.PromoCode{MovieIDs: pq.Int64Array{3}, StudioIDs: pq.Int64Array{1, 2}}, nil},
+		{"other movie", models.PromoCode{MovieIDs: pq.Int64Array{4}}, ErrPromoNotApplicable},
+		{"other studio", models.PromoCode{StudioIDs: pq.Int64Array{1}}, ErrPromoNotApplicable},
+		{"uses left", models.PromoCode{MaxUses: 5, UsedCount: 4}, nil},
+		{"used up", models.PromoCode{MaxUses: 5, UsedCount: 5}, ErrPromoExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPromoCode(tt.promo, showtime, now)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPromoDiscount(t *testing.T) {
+	items := []models.BookingItem{{Price: 1200}, {Price: 840}, {Price: 1500}, {Price: 1200}}
+
+	tests := []struct {
+		name  string
+		promo models.PromoCode
+		want  int64
+	}{
+		{"percent", models.PromoCode{Kind: models.PromoPercent, Value: 10}, 474},
+		{"fixed", models.PromoCode{Kind: models.PromoFixed, Value: 500}, 500},
+		{"fixed above total", models.PromoCode{Kind: models.PromoFixed, Value: 10000}, 4740},
+		{"buy one get one", models.PromoCode{Kind: models.PromoBuyNGetM, BuyCount: 1, FreeCount: 1}, 840 + 1200},
+		{"buy three get one", models.PromoCode{Kind: models.PromoBuyNGetM, BuyCount: 3, FreeCount: 1}, 840},
+		{"incomplete bundle", models.PromoCode{Kind: models.PromoBuyNGetM, BuyCount: 4, FreeCount: 1}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, promoDiscount(tt.promo, items))
+		})
+	}
+}
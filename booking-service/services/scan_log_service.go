@@ -0,0 +1,326 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultScanLogLimit is how many scans a page of the scan log holds
+	// unless asked otherwise, and maxScanLogLimit the most it may hold.
+	defaultScanLogLimit = 100
+	maxScanLogLimit     = 1000
+
+	// maxStatsScans caps how many scans stats are worked out from, and
+	// maxStatsBuckets how many intervals their throughput is split into.
+	maxStatsScans   = 100000
+	maxStatsBuckets = 10000
+)
+
+var (
+	ErrScanLimit    = fmt.Errorf("limit must be between 1 and %d", maxScanLogLimit)
+	ErrScanRange    = errors.New("from must be before to")
+	ErrStatsScope   = errors.New("give a showtimeId or both from and to")
+	ErrStatsTooMany = fmt.Errorf("stats cover at most %d scans; narrow the query", maxStatsScans)
+	ErrScanInterval = errors.New("interval must be between 1 and 60 minutes")
+	ErrStatsSpan    = fmt.Errorf("stats cover at most %d intervals; use a longer interval or narrow the query", maxStatsBuckets)
+)
+
+// scanLogColumns are the columns of a scan log export, in order.
+var scanLogColumns = []string{
+	"id", "scanned_at", "source", "direction", "result", "reason",
+	"booking_code", "booking_id", "showtime_id", "studio_id", "device_id",
+	"staff_id", "staff_role", "seat_ids", "duplicate_of", "uploaded_by", "created_at",
+}
+
+// filterScans narrows db to the scans a query matches.
+func filterScans(db *gorm.DB, query models.ScanQuery) *gorm.DB {
+	db = db.Model(&models.ScanEvent{})
+	if query.ShowtimeID != 0 {
+		db = db.Where("showtime_id = ?", query.ShowtimeID)
+	}
+	if query.StudioID != 0 {
+		db = db.Where("studio_id = ?", query.StudioID)
+	}
+	if query.BookingCode != "" {
+		db = db.Where("booking_code = ?", query.BookingCode)
+	}
+	if query.DeviceID != "" {
+		db = db.Where("device_id = ?", query.DeviceID)
+	}
+	if query.StaffID != 0 {
+		db = db.Where("staff_id = ?", query.StaffID)
+	}
+	if query.Source != "" {
+		db = db.Where("source = ?", query.Source)
+	}
+	if query.Direction != "" {
+		db = db.Where("direction = ?", query.Direction)
+	}
+	if query.Result != "" {
+		db = db.Where("result = ?", query.Result)
+	}
+	if query.Reason != "" {
+		db = db.Where("reason = ?", query.Reason)
+	}
+	if query.From != nil {
+		db = db.Where("scanned_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("scanned_at < ?", *query.To)
+	}
+	return db
+}
+
+// checkScanRange refuses a query whose time range is back to front.
+func checkScanRange(query models.ScanQuery) error {
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return ErrScanRange
+	}
+	return nil
+}
+
+// ListScans returns one page of the scans a query matches, newest first,
+// with how many match in all.
+func ListScans(query models.ScanQuery) (*models.ScanLog, error) {
+	if query.Limit == 0 {
+		query.Limit = defaultScanLogLimit
+	}
+	if query.Limit < 0 || query.Limit > maxScanLogLimit {
+		return nil, ErrScanLimit
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	if err := checkScanRange(query); err != nil {
+		return nil, err
+	}
+
+	log := &models.ScanLog{Limit: query.Limit, Offset: query.Offset, Scans: []models.ScanEvent{}}
+	if err := filterScans(database.DB, query).Count(&log.Total).Error; err != nil {
+		return nil, err
+	}
+	err := filterScans(database.DB, query).
+		Order("scanned_at DESC, id DESC").
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Find(&log.Scans).Error
+	if err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// csvText keeps a spreadsheet opening the export from reading a value
+// scanners or staff supplied as a formula.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// scanLogRow is one scan as a row of the export.
+func scanLogRow(event models.ScanEvent) []string {
+	optional := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*id), 10)
+	}
+	seatIDs := make([]string, len(event.SeatIDs))
+	for i, id := range event.SeatIDs {
+		seatIDs[i] = strconv.FormatInt(id, 10)
+	}
+	return []string{
+		strconv.FormatUint(uint64(event.ID), 10),
+		event.ScannedAt.UTC().Format(time.RFC3339Nano),
+		event.Source,
+		event.Direction,
+		event.Result,
+		event.Reason,
+		csvText(event.BookingCode),
+		optional(event.BookingID),
+		strconv.FormatUint(uint64(event.ShowtimeID), 10),
+		strconv.FormatUint(uint64(event.StudioID), 10),
+		csvText(event.DeviceID),
+		optional(event.StaffID),
+		csvText(event.StaffRole),
+		strings.Join(seatIDs, " "),
+		optional(event.DuplicateOf),
+		optional(event.UploadedBy),
+		event.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// ExportScans writes every scan a query matches to w as CSV, oldest first.
+// Scans are streamed from the database rather than loaded at once, and
+// the query's limit and offset are ignored.
+func ExportScans(query models.ScanQuery, w io.Writer) error {
+	if err := checkScanRange(query); err != nil {
+		return err
+	}
+
+	rows, err := filterScans(database.DB, query).Order("scanned_at, id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	out := csv.NewWriter(w)
+	if err := out.Write(scanLogColumns); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var event models.ScanEvent
+		if err := database.DB.ScanRows(rows, &event); err != nil {
+			return err
+		}
+		if err := out.Write(scanLogRow(event)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// GetScanStats sums up the scans of a showtime or a time range: outcomes,
+// refusal reasons, scanners and entry throughput in buckets of interval.
+func GetScanStats(query models.ScanQuery, interval time.Duration) (*models.ScanStats, error) {
+	if query.ShowtimeID == 0 && (query.From == nil || query.To == nil) {
+		return nil, ErrStatsScope
+	}
+	if interval < time.Minute || interval > time.Hour {
+		return nil, ErrScanInterval
+	}
+	if err := checkScanRange(query); err != nil {
+		return nil, err
+	}
+
+	var events []models.ScanEvent
+	err := filterScans(database.DB, query).
+		Order("scanned_at, id").
+		Limit(maxStatsScans + 1).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > maxStatsScans {
+		return nil, ErrStatsTooMany
+	}
+	if n := len(events); n > 0 && events[n-1].ScannedAt.Sub(events[0].ScannedAt)/interval >= maxStatsBuckets {
+		return nil, ErrStatsSpan
+	}
+	return scanStats(events, interval), nil
+}
+
+// scanRefused tells whether a scan let nobody through.
+func scanRefused(result string) bool {
+	switch result {
+	case models.ScanRejected, models.ScanInvalid, models.ScanDuplicate:
+		return true
+	}
+	return false
+}
+
+// admittedSeats is how many seats an admission let in. Admissions from
+// before seats were recorded count as one.
+func admittedSeats(event models.ScanEvent) int {
+	if len(event.SeatIDs) == 0 {
+		return 1
+	}
+	return len(event.SeatIDs)
+}
+
+// scanStats sums up scans ordered by when they were made. Throughput
+// buckets run from the first to the last scan without gaps, so quiet
+// intervals show as zero.
+func scanStats(events []models.ScanEvent, interval time.Duration) *models.ScanStats {
+	stats := &models.ScanStats{
+		Total:           len(events),
+		Results:         map[string]int{},
+		Reasons:         map[string]int{},
+		Devices:         []models.DeviceScanStats{},
+		IntervalMinutes: int(interval / time.Minute),
+		Throughput:      []models.ScanBucket{},
+	}
+	if len(events) == 0 {
+		return stats
+	}
+
+	devices := map[string]*models.DeviceScanStats{}
+	first := events[0].ScannedAt.Truncate(interval)
+	last := events[len(events)-1].ScannedAt.Truncate(interval)
+	buckets := make([]models.ScanBucket, int(last.Sub(first)/interval)+1)
+	for i := range buckets {
+		buckets[i].Start = first.Add(time.Duration(i) * interval)
+	}
+
+	for _, event := range events {
+		stats.Results[event.Result]++
+		if event.Reason != "" {
+			stats.Reasons[event.Reason]++
+		}
+
+		device, ok := devices[event.DeviceID]
+		if !ok {
+			device = &models.DeviceScanStats{DeviceID: event.DeviceID}
+			devices[event.DeviceID] = device
+		}
+		device.Scans++
+
+		bucket := &buckets[int(event.ScannedAt.Truncate(interval).Sub(first)/interval)]
+		switch {
+		case event.Result == models.ScanAdmitted:
+			seats := admittedSeats(event)
+			device.Admitted++
+			bucket.Admitted++
+			bucket.Seats += seats
+			stats.AdmittedSeats += seats
+			at := event.ScannedAt
+			if stats.FirstAdmissionAt == nil {
+				stats.FirstAdmissionAt = &at
+			}
+			stats.LastAdmissionAt = &at
+		case scanRefused(event.Result):
+			device.Refused++
+			bucket.Refused++
+		}
+	}
+
+	for _, device := range devices {
+		stats.Devices = append(stats.Devices, *device)
+	}
+	sort.Slice(stats.Devices, func(a, b int) bool { return stats.Devices[a].DeviceID < stats.Devices[b].DeviceID })
+
+	for _, bucket := range buckets {
+		if bucket.Seats > stats.PeakSeats {
+			stats.PeakSeats = bucket.Seats
+		}
+	}
+	stats.Throughput = buckets
+
+	// Entries over less than a minute are taken as a minute's worth.
+	if stats.FirstAdmissionAt != nil {
+		span := stats.LastAdmissionAt.Sub(*stats.FirstAdmissionAt)
+		if span < time.Minute {
+			span = time.Minute
+		}
+		stats.SeatsPerMinute = float64(stats.AdmittedSeats) / span.Minutes()
+	}
+	return stats
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"booking-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanStats(t *testing.T) {
+	start := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	events := []models.ScanEvent{
+		{DeviceID: "door-1", Result: models.ScanAdmitted, SeatIDs: []int64{1, 2}, ScannedAt: start.Add(time.Minute)},
+		{DeviceID: "door-2", Result: models.ScanRejected, Reason: models.RejectWrongStudio, ScannedAt: start.Add(3 * time.Minute)},
+		{DeviceID: "door-1", Result: models.ScanAdmitted, SeatIDs: []int64{3}, ScannedAt: start.Add(4 * time.Minute)},
+		{DeviceID: "door-1", Result: models.ScanDuplicate, Reason: models.RejectAlreadyUsed, ScannedAt: start.Add(5 * time.Minute)},
+		{DeviceID: "door-2", Result: models.ScanInvalid, Reason: models.RejectBadSignature, ScannedAt: start.Add(6 * time.Minute)},
+		{DeviceID: "door-2", Result: models.ScanExited, SeatIDs: []int64{3}, ScannedAt: start.Add(12 * time.Minute)},
+		{DeviceID: "door-2", Result: models.ScanAdmitted, ScannedAt: start.Add(16 * time.Minute)},
+	}
+
+	stats := scanStats(events, 5*time.Minute)
+
+	assert.Equal(t, 7, stats.Total)
+	assert.Equal(t, map[string]int{
+		models.ScanAdmitted:  3,
+		models.ScanRejected:  1,
+		models.ScanDuplicate: 1,
+		models.ScanInvalid:   1,
+		models.ScanExited:    1,
+	}, stats.Results)
+	assert.Equal(t, map[string]int{
+		models.RejectWrongStudio:  1,
+		models.RejectAlreadyUsed:  1,
+		models.RejectBadSignature: 1,
+	}, stats.Reasons)
+	assert.Equal(t, []models.DeviceScanStats{
+		{DeviceID: "door-1", Scans: 3, Admitted: 2, Refused: 1},
+		{DeviceID: "door-2", Scans: 4, Admitted: 1, Refused: 2},
+	}, stats.Devices)
+
+	assert.Equal(t, 4, stats.AdmittedSeats)
+	assert.Equal(t, start.Add(time.Minute), *stats.FirstAdmissionAt)
+	assert.Equal(t, start.Add(16*time.Minute), *stats.LastAdmissionAt)
+	assert.InDelta(t, 4.0/15, stats.SeatsPerMinute, 1e-9)
+	assert.Equal(t, 3, stats.PeakSeats)
+	assert.Equal(t, 5, stats.IntervalMinutes)
+	assert.Equal(t, []models.ScanBucket{
+		{Start: start, Admitted: 2, Seats: 3, Refused: 1},
+		{Start: start.Add(5 * time.Minute), Refused: 2},
+		{Start: start.Add(10 * time.Minute)},
+		{Start: start.Add(15 * time.Minute), Admitted: 1, Seats: 1},
+	}, stats.Throughput)
+}
+
+func TestScanStatsWithoutScans(t *testing.T) {
+	stats := scanStats(nil, 5*time.Minute)
+
+	assert.Equal(t, 0, stats.Total)
+	assert.Empty(t, stats.Throughput)
+	assert.Nil(t, stats.FirstAdmissionAt)
+	assert.Zero(t, stats.SeatsPerMinute)
+}
+
+func TestScanLogRow(t *testing.T) {
+	bookingID, staffID := uint(7), uint(3)
+	at := time.Date(2024, 5, 1, 18, 2, 11, 0, time.UTC)
+	event := models.ScanEvent{
+		ID:          42,
+		BookingID:   &bookingID,
+		BookingCode: "BOOK123",
+		ShowtimeID:  4,
+		StudioID:    2,
+		DeviceID:    "door-1",
+		StaffID:     &staffID,
+		StaffRole:   "cashier",
+		Source:      models.ScanOnline,
+		Direction:   models.ScanEntry,
+		SeatIDs:     []int64{7, 8},
+		Result:      models.ScanAdmitted,
+		ScannedAt:   at,
+		CreatedAt:   at,
+	}
+
+	row := scanLogRow(event)
+	assert.Len(t, row, len(scanLogColumns))
+	assert.Equal(t, []string{
+		"42", "2024-05-01T18:02:11Z", "online", "entry", "admitted", "",
+		"BOOK123", "7", "4", "2", "door-1",
+		"3", "cashier", "7 8", "", "", "2024-05-01T18:02:11Z",
+	}, row)
+}
+
+func TestScanLogRowNeutralisesFormulas(t *testing.T) {
+	event := models.ScanEvent{
+		BookingCode: "@SUM(A1)",
+		DeviceID:    "=HYPERLINK(\"http://evil.example\")",
+		StaffRole:   "+cashier",
+	}
+
+	row := scanLogRow(event)
+	assert.Equal(t, "'@SUM(A1)", row[6])
+	assert.Equal(t, "'=HYPERLINK(\"http://evil.example\")", row[10])
+	assert.Equal(t, "'+cashier", row[12])
+	assert.Equal(t, "door-1", csvText("door-1"))
+	assert.Equal(t, "'-1", csvText("-1"))
+}
+
+func TestScanQueryValidation(t *testing.T) {
+	from := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	_, err := ListScans(models.ScanQuery{Limit: maxScanLogLimit + 1})
+	assert.ErrorIs(t, err, ErrScanLimit)
+
+	_, err = ListScans(models.ScanQuery{From: &to, To: &from})
+	assert.ErrorIs(t, err, ErrScanRange)
+
+	assert.ErrorIs(t, ExportScans(models.ScanQuery{From: &to, To: &from}, nil), ErrScanRange)
+
+	tests := []struct {
+		name     string
+		query    models.ScanQuery
+		interval time.Duration
+		want     error
+	}{
+		{"no scope", models.ScanQuery{StudioID: 2}, 5 * time.Minute, ErrStatsScope},
+		{"open range", models.ScanQuery{From: &from}, 5 * time.Minute, ErrStatsScope},
+		{"short interval", models.ScanQuery{ShowtimeID: 4}, time.Second, ErrScanInterval},
+		{"long interval", models.ScanQuery{ShowtimeID: 4}, 2 * time.Hour, ErrScanInterval},
+		{"backwards range", models.ScanQuery{From: &to, To: &from}, 5 * time.Minute, ErrScanRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := GetScanStats(tt.query, tt.interval)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Nil(t, stats)
+		})
+	}
+}
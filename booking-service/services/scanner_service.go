@@ -0,0 +1,334 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxScansPerUpload caps how many scans a scanner may upload at once.
+const maxScansPerUpload = 500
+
+var (
+	ErrBundleScope    = errors.New("give a showtimeId or a date")
+	ErrDeviceRequired = errors.New("deviceId is required")
+	ErrNoScans        = errors.New("scans must contain at least one scan")
+	ErrTooManyScans   = fmt.Errorf("an upload may contain at most %d scans", maxScansPerUpload)
+	ErrScanDirection  = errors.New("direction must be entry or exit")
+)
+
+// scannerKeys lists the keys tickets are accepted from, ordered by key ID.
+func scannerKeys(keys map[string]ed25519.PublicKey) []models.ScannerKey {
+	list := make([]models.ScannerKey, 0, len(keys))
+	for id, key := range keys {
+		list = append(list, models.ScannerKey{
+			KeyID:     id,
+			Algorithm: "Ed25519",
+			PublicKey: base64.StdEncoding.EncodeToString(key),
+		})
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].KeyID < list[b].KeyID })
+	return list
+}
+
+// scannerShowtime describes a showtime to a scanner, admission window and
+// end of re-entry included.
+func scannerShowtime(showtime utils.Showtime) models.ScannerShowtime {
+	opens, closes := admissionWindow(showtime.StartTime)
+	return models.ScannerShowtime{
+		ID:                showtime.ID,
+		StudioID:          showtime.StudioID,
+		StartTime:         showtime.StartTime,
+		AdmissionOpensAt:  opens,
+		AdmissionClosesAt: closes,
+		ReentryClosesAt:   showtime.StartTime.Add(reentryClosesAfter()),
+	}
+}
+
+// scannerTicket describes a booking to a scanner, with which of its seats
+// got in and which are inside.
+func scannerTicket(booking models.Booking) models.ScannerTicket {
+	ticket := models.ScannerTicket{
+		BookingCode:     booking.BookingCode,
+		StudioID:        booking.StudioID,
+		ShowtimeID:      booking.ShowtimeID,
+		SeatIDs:         bookingSeatIDs(booking),
+		AdmittedSeatIDs: []uint{},
+		InsideSeatIDs:   []uint{},
+	}
+	for _, item := range booking.Items {
+		if item.CancelledAt != nil || item.AdmittedAt == nil {
+			continue
+		}
+		ticket.AdmittedSeatIDs = append(ticket.AdmittedSeatIDs, item.SeatID)
+		if item.Inside() {
+			ticket.InsideSeatIDs = append(ticket.InsideSeatIDs, item.SeatID)
+		}
+	}
+	return ticket
+}
+
+// GetScannerBundle builds the bundle a scanner needs to validate tickets
+// offline for one showtime, or for every showtime on a day (YYYY-MM-DD),
+// optionally in one studio. Only active and used bookings are listed, so
+// tickets replaced by a seat change or cancelled are refused by the scanner
+// too; used bookings stay listed for their seats to get in again.
+func GetScannerBundle(showtimeID uint, date string, studioID uint) (*models.ScannerBundle, error) {
+	var showtimes []utils.Showtime
+	switch {
+	case showtimeID != 0:
+		showtime, err := utils.GetShowtime(showtimeID)
+		if err != nil {
+			return nil, err
+		}
+		showtimes = []utils.Showtime{*showtime}
+	case date != "":
+		var err error
+		showtimes, err = utils.GetShowtimes(date, studioID)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrBundleScope
+	}
+
+	bundle := &models.ScannerBundle{
+		GeneratedAt:   time.Now(),
+		ReentryPolicy: reentryPolicy(),
+		ReentryLimit:  reentryLimit(),
+		Showtimes:     make([]models.ScannerShowtime, len(showtimes)),
+		Keys:          scannerKeys(utils.TicketPublicKeys()),
+		Tickets:       []models.ScannerTicket{},
+	}
+	showtimeIDs := make([]uint, len(showtimes))
+	for i, showtime := range showtimes {
+		bundle.Showtimes[i] = scannerShowtime(showtime)
+		showtimeIDs[i] = showtime.ID
+	}
+	if len(showtimeIDs) == 0 {
+		return bundle, nil
+	}
+
+	var bookings []models.Booking
+	err := database.DB.Preload("Items").
+		Where("showtime_id IN ? AND status IN ?", showtimeIDs, []models.BookingStatus{models.BookingActive, models.BookingUsed}).
+		Order("id").
+		Find(&bookings).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, booking := range bookings {
+		bundle.Tickets = append(bundle.Tickets, scannerTicket(booking))
+	}
+	return bundle, nil
+}
+
+// scanOrder returns the indexes of scans ordered by when they were made,
+// keeping the upload order for scans made at the same time.
+func scanOrder(scans []models.OfflineScan) []int {
+	order := make([]int, len(scans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scans[order[a]].ScannedAt.Before(scans[order[b]].ScannedAt)
+	})
+	return order
+}
+
+// settleScan decides a scan of a locked booking. An entry lets the seats
+// in that may get in now, and an exit scans the seats inside out. A seat
+// that is already inside, or was admitted and may not get in again, makes
+// the scan a duplicate, pointing at the latest admission of the booking.
+// Anything else rejects the scan with its reason.
+func settleScan(tx *gorm.DB, booking *models.Booking, event *models.ScanEvent, seatIDs []uint, showtimes showtimeCache, actor *models.User) error {
+	event.BookingID = &booking.ID
+
+	var passed []uint
+	var err error
+	if event.Direction == models.ScanExit {
+		passed, err = exitSeats(tx, booking, seatIDs, event.ScannedAt)
+	} else if err = checkBookingStatus(booking.Status); err == nil {
+		var showtime *utils.Showtime
+		showtime, err = showtimes.get(booking.ShowtimeID)
+		if err != nil {
+			return err
+		}
+		passed, err = admitSeats(tx, booking, seatIDs, showtime.StartTime, event.ScannedAt, actor, "scanned offline by "+event.DeviceID)
+	}
+	event.SeatIDs = toInt64Array(passed)
+
+	var refused *AdmissionError
+	if errors.As(err, &refused) && (refused.Reason == models.RejectAlreadyUsed || refused.Reason == models.RejectAlreadyInside) {
+		event.Result = models.ScanDuplicate
+		event.Reason = refused.Reason
+		var latest models.ScanEvent
+		err := tx.Where("booking_id = ? AND result = ?", booking.ID, models.ScanAdmitted).Order("scanned_at DESC, id DESC").First(&latest).Error
+		if err == nil {
+			event.DuplicateOf = &latest.ID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return nil
+	}
+	return applyScanResult(event, err)
+}
+
+// applyScanResult sets the result of a scan from the outcome of checking
+// it, returning only errors that are not refusals.
+func applyScanResult(event *models.ScanEvent, err error) error {
+	var refused *AdmissionError
+	switch {
+	case err == nil && event.Direction == models.ScanExit:
+		event.Result = models.ScanExited
+	case err == nil:
+		event.Result = models.ScanAdmitted
+	case errors.As(err, &refused):
+		event.Result = models.ScanRejected
+		event.Reason = refused.Reason
+	case errors.Is(err, ErrInvalidTransition):
+		event.Result = models.ScanRejected
+		event.Reason = models.RejectNotActive
+	default:
+		return err
+	}
+	return nil
+}
+
+// scanDirection is which way an uploaded scan went, entry unless given.
+func scanDirection(scan models.OfflineScan) string {
+	if scan.Direction == "" {
+		return models.ScanEntry
+	}
+	return scan.Direction
+}
+
+// reconcileScan records one offline scan and applies it to its booking. A
+// scan the device already uploaded is not applied again; the earlier
+// record is returned instead.
+func reconcileScan(upload models.ScanUpload, scan models.OfflineScan, showtimes showtimeCache, actor models.User) (*models.ScanEvent, error) {
+	event := models.ScanEvent{
+		StudioID:   upload.StudioID,
+		DeviceID:   upload.DeviceID,
+		StaffID:    &actor.ID,
+		StaffRole:  actor.Role,
+		Source:     models.ScanOffline,
+		Direction:  scanDirection(scan),
+		UploadedBy: &actor.ID,
+		ScannedAt:  scan.ScannedAt.Truncate(time.Millisecond),
+	}
+	if event.ScannedAt.IsZero() {
+		event.ScannedAt = time.Now().Truncate(time.Millisecond)
+	}
+
+	ticket, err := utils.VerifyTicket(scan.Ticket)
+	if err != nil {
+		event.Result = models.ScanInvalid
+		var refused *AdmissionError
+		if errors.As(ticketRejection(err), &refused) {
+			event.Reason = refused.Reason
+		}
+		return &event, database.DB.Create(&event).Error
+	}
+	event.BookingCode = ticket.BookingCode
+	event.ShowtimeID = ticket.ShowtimeID
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_code = ?", ticket.BookingCode).
+			First(&booking).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			event.Result = models.ScanRejected
+			event.Reason = models.RejectNotFound
+			return tx.Create(&event).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// Scanners retry uploads that got no answer. The booking lock
+		// keeps two retries from both getting past this check.
+		var previous models.ScanEvent
+		err = tx.Where("device_id = ? AND source = ? AND direction = ? AND booking_code = ? AND scanned_at = ?",
+			upload.DeviceID, models.ScanOffline, event.Direction, ticket.BookingCode, event.ScannedAt).
+			First(&previous).Error
+		if err == nil {
+			event = previous
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := checkStudio(*ticket, upload.StudioID); err != nil {
+			event.BookingID = &booking.ID
+			if err := applyScanResult(&event, err); err != nil {
+				return err
+			}
+		} else if err := settleScan(tx, &booking, &event, scan.SeatIDs, showtimes, &actor); err != nil {
+			return err
+		}
+		return tx.Create(&event).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// UploadScans reconciles the scans a scanner made offline. Scans are
+// applied in the order they were made, each in its own transaction. When
+// two devices admitted the same ticket, the scan booking service hears of
+// first counts as the admission and the other is flagged as a duplicate.
+func UploadScans(upload models.ScanUpload, actor models.User) (*models.ScanUploadResult, error) {
+	if upload.DeviceID == "" {
+		return nil, ErrDeviceRequired
+	}
+	if upload.StudioID == 0 {
+		return nil, ErrStudioRequired
+	}
+	if len(upload.Scans) == 0 {
+		return nil, ErrNoScans
+	}
+	if len(upload.Scans) > maxScansPerUpload {
+		return nil, ErrTooManyScans
+	}
+	for _, scan := range upload.Scans {
+		if direction := scanDirection(scan); direction != models.ScanEntry && direction != models.ScanExit {
+			return nil, ErrScanDirection
+		}
+	}
+
+	result := &models.ScanUploadResult{Scans: make([]models.ScanEvent, len(upload.Scans))}
+	showtimes := showtimeCache{}
+	for _, i := range scanOrder(upload.Scans) {
+		event, err := reconcileScan(upload, upload.Scans[i], showtimes, actor)
+		if err != nil {
+			return nil, err
+		}
+		result.Scans[i] = *event
+
+		switch event.Result {
+		case models.ScanAdmitted:
+			result.Admitted++
+		case models.ScanExited:
+			result.Exited++
+		case models.ScanDuplicate:
+			result.Duplicates++
+		default:
+			result.Rejected++
+		}
+	}
+	return result, nil
+}
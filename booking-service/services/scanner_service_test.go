@@ -0,0 +1,83 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"booking-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScannerKeys(t *testing.T) {
+	first := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	second := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	second[0] = 1
+
+	keys := scannerKeys(map[string]ed25519.PublicKey{"k2": second, "k1": first})
+
+	assert.Equal(t, []models.ScannerKey{
+		{KeyID: "k1", Algorithm: "Ed25519", PublicKey: base64.StdEncoding.EncodeToString(first)},
+		{KeyID: "k2", Algorithm: "Ed25519", PublicKey: base64.StdEncoding.EncodeToString(second)},
+	}, keys)
+}
+
+func TestScanOrder(t *testing.T) {
+	start := time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC)
+	scans := []models.OfflineScan{
+		{Ticket: "c", ScannedAt: start.Add(2 * time.Minute)},
+		{Ticket: "a", ScannedAt: start},
+		{Ticket: "b", ScannedAt: start.Add(time.Minute)},
+		{Ticket: "b again", ScannedAt: start.Add(time.Minute)},
+	}
+
+	assert.Equal(t, []int{1, 2, 3, 0}, scanOrder(scans))
+}
+
+func TestUploadScansValidation(t *testing.T) {
+	actor := models.User{ID: 1, Role: "cashier"}
+	scan := models.OfflineScan{Ticket: "T1.k1.AA.AA", ScannedAt: time.Now()}
+
+	tests := []struct {
+		name   string
+		upload models.ScanUpload
+		want   error
+	}{
+		{"no device", models.ScanUpload{StudioID: 5, Scans: []models.OfflineScan{scan}}, ErrDeviceRequired},
+		{"no studio", models.ScanUpload{DeviceID: "door-1", Scans: []models.OfflineScan{scan}}, ErrStudioRequired},
+		{"no scans", models.ScanUpload{DeviceID: "door-1", StudioID: 5}, ErrNoScans},
+		{"too many scans", models.ScanUpload{DeviceID: "door-1", StudioID: 5, Scans: make([]models.OfflineScan, maxScansPerUpload+1)}, ErrTooManyScans},
+		{"unknown direction", models.ScanUpload{DeviceID: "door-1", StudioID: 5, Scans: []models.OfflineScan{{Ticket: scan.Ticket, Direction: "sideways"}}}, ErrScanDirection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := UploadScans(tt.upload, actor)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestScannerTicket(t *testing.T) {
+	at := time.Date(2024, 5, 1, 18, 50, 0, 0, time.UTC)
+	booking := models.Booking{
+		BookingCode: "BOOK123",
+		StudioID:    5,
+		ShowtimeID:  1,
+		SeatIDs:     []int64{1, 2, 3},
+		Items: []models.BookingItem{
+			{SeatID: 1, AdmittedAt: &at},
+			{SeatID: 2, AdmittedAt: &at, ExitedAt: &at},
+			{SeatID: 3},
+			{SeatID: 4, AdmittedAt: &at, CancelledAt: &at},
+		},
+	}
+
+	ticket := scannerTicket(booking)
+	assert.Equal(t, []uint{1, 2, 3}, ticket.SeatIDs)
+	assert.Equal(t, []uint{1, 2}, ticket.AdmittedSeatIDs)
+	assert.Equal(t, []uint{1}, ticket.InsideSeatIDs)
+}
@@ -3,19 +3,81 @@ package utils
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 )
 
+// Showtime is what booking service needs to know about a showtime.
+type Showtime struct {
+	ID        uint      `json:"id"`
+	MovieID   uint      `json:"movie_id"`
+	StudioID  uint      `json:"studio_id"`
+	StartTime time.Time `json:"start_time"`
+	Format    string    `json:"format"`
+}
+
+// Seat is a studio seat as cinema service describes it.
+type Seat struct {
+	ID         uint   `json:"id"`
+	SeatNumber string `json:"seat_number"`
+	SeatType   string `json:"seat_type"`
+}
+
+// SeatConflict is a seat cinema service refused to sell and the state it
+// was in.
+type SeatConflict struct {
+	SeatID     uint   `json:"seatId"`
+	SeatNumber string `json:"seatNumber,omitempty"`
+	Status     string `json:"status"`
+}
+
+// SeatGap is a single empty seat a selection would leave stranded.
+type SeatGap struct {
+	SeatID     uint   `json:"seatId"`
+	SeatNumber string `json:"seatNumber"`
+	Row        string `json:"row"`
+}
+
+// SeatConflictError is returned when cinema service refuses to sell the
+// requested seats, either because some are not available or because the
+// studio does not allow the single-seat gaps they would leave.
+type SeatConflictError struct {
+	Message string
+	Seats   []SeatConflict
+	Gaps    []SeatGap
+}
+
+func (e *SeatConflictError) Error() string {
+	return e.Message
+}
+
+// SeatRequestError is returned when cinema service rejects the seat request
+// itself, for example because a seat belongs to another studio.
+type SeatRequestError struct {
+	Message string
+	SeatIDs []uint
+}
+
+func (e *SeatRequestError) Error() string {
+	return e.Message
+}
+
 var (
 	authServiceURL   string
 	cinemaServiceURL string
+	serviceToken     string
 )
 
 func init() {
 	authServiceURL = os.Getenv("AUTH_SERVICE_URL")
 	cinemaServiceURL = os.Getenv("CINEMA_SERVICE_URL")
+	serviceToken = os.Getenv("SERVICE_TOKEN")
 	if authServiceURL == "" {
 		authServiceURL = "http://localhost:3001"
 	}
@@ -24,20 +86,200 @@ func init() {
 	}
 }
 
-func ReserveSeats(seatIDs []uint) error {
-	reqBody := map[string][]uint{"seatIds": seatIDs}
+// seatClaimError turns a failed hold response into an error,
+// keeping cinema service's explanation when it gives one.
+func seatClaimError(resp *http.Response) error {
+	var body struct {
+		Error     string         `json:"error"`
+		SeatIDs   []uint         `json:"seatIds"`
+		Conflicts []SeatConflict `json:"conflicts"`
+		Gaps      []SeatGap      `json:"gaps"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+
+	switch resp.StatusCode {
+	case http.StatusConflict:
+		if decodeErr != nil || body.Error == "" {
+			body.Error = "some seats are not available"
+		}
+		return &SeatConflictError{Message: body.Error, Seats: body.Conflicts, Gaps: body.Gaps}
+	case http.StatusBadRequest, http.StatusNotFound:
+		if decodeErr == nil && body.Error != "" {
+			return &SeatRequestError{Message: body.Error, SeatIDs: body.SeatIDs}
+		}
+	}
+	return fmt.Errorf("failed to reserve seats")
+}
+
+// cinemaLookupError turns a failed lookup response into an error, passing
+// on cinema service's explanation when the request itself was at fault.
+func cinemaLookupError(resp *http.Response, fallback string) error {
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusNotFound {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.Error != "" {
+			return &SeatRequestError{Message: body.Error}
+		}
+	}
+	return errors.New(fallback)
+}
+
+// GetShowtime looks a showtime up in cinema service.
+func GetShowtime(showtimeID uint) (*Showtime, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/api/cinema/showtimes/%d", cinemaServiceURL, showtimeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch showtime")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cinemaLookupError(resp, "failed to fetch showtime")
+	}
+
+	var showtime Showtime
+	if err := json.NewDecoder(resp.Body).Decode(&showtime); err != nil {
+		return nil, fmt.Errorf("failed to fetch showtime")
+	}
+	return &showtime, nil
+}
+
+// GetShowtimes lists the showtimes on a day (YYYY-MM-DD), optionally in
+// one studio.
+func GetShowtimes(date string, studioID uint) ([]Showtime, error) {
+	query := url.Values{"date": {date}}
+	if studioID != 0 {
+		query.Set("studio_id", strconv.FormatUint(uint64(studioID), 10))
+	}
+	resp, err := http.Get(cinemaServiceURL + "/api/cinema/showtimes?" + query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch showtimes")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cinemaLookupError(resp, "failed to fetch showtimes")
+	}
+
+	var showtimes []Showtime
+	if err := json.NewDecoder(resp.Body).Decode(&showtimes); err != nil {
+		return nil, fmt.Errorf("failed to fetch showtimes")
+	}
+	return showtimes, nil
+}
+
+// GetStudioSeats lists the seats of the studio a showtime plays in.
+func GetStudioSeats(studioID, showtimeID uint) ([]Seat, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/api/cinema/studios/%d/seats?showtime_id=%d", cinemaServiceURL, studioID, showtimeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch seats")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cinemaLookupError(resp, "failed to fetch seats")
+	}
+
+	var seats []Seat
+	if err := json.NewDecoder(resp.Body).Decode(&seats); err != nil {
+		return nil, fmt.Errorf("failed to fetch seats")
+	}
+	return seats, nil
+}
+
+// ReleaseSeats puts sold seats of a showtime back on sale. Failures are
+// logged: the seats stay sold until someone releases them by hand.
+func ReleaseSeats(showtimeID uint, seatIDs []uint) {
+	reqBody := map[string]interface{}{"showtimeId": showtimeID, "seatIds": seatIDs}
+	if err := postRelease("/api/cinema/seats/release", reqBody); err != nil {
+		log.Printf("Failed to release seats %v of showtime %d: %v", seatIDs, showtimeID, err)
+	}
+}
+
+// postCinema sends a JSON request to one of cinema service's seat
+// endpoints, which only answer other services.
+func postCinema(path string, reqBody interface{}) (*http.Response, error) {
 	jsonData, _ := json.Marshal(reqBody)
-	
-	resp, err := http.Post(cinemaServiceURL+"/api/cinema/seats/reserve", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil || resp.StatusCode != 200 {
-		return fmt.Errorf("failed to reserve seats")
+
+	req, err := http.NewRequest(http.MethodPost, cinemaServiceURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Service-Token", serviceToken)
+	return http.DefaultClient.Do(req)
+}
+
+// postRelease sends a release request to cinema service.
+func postRelease(path string, reqBody interface{}) error {
+	resp, err := postCinema(path, reqBody)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cinema service answered %s", resp.Status)
 	}
-	resp.Body.Close()
 	return nil
 }
 
-func ReleaseSeats(seatIDs []uint) {
-	reqBody := map[string][]uint{"seatIds": seatIDs}
-	jsonData, _ := json.Marshal(reqBody)
-	http.Post(cinemaServiceURL+"/api/cinema/seats/release", "application/json", bytes.NewBuffer(jsonData))
-}
\ No newline at end of file
+// HoldSeats sets seats aside in cinema service and returns the hold token,
+// along with the single seats the hold strands in studios that only warn
+// about them. Cinema service checks that the showtime plays in the studio
+// and that the seats belong to it. Held seats go back on sale by
+// themselves if the hold is never confirmed.
+func HoldSeats(studioID, showtimeID uint, seatIDs []uint) (string, []SeatGap, error) {
+	reqBody := map[string]interface{}{"studioId": studioID, "showtimeId": showtimeID, "seatIds": seatIDs}
+	resp, err := postCinema("/api/cinema/seats/hold", reqBody)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to reserve seats")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", nil, seatClaimError(resp)
+	}
+
+	var hold struct {
+		HoldToken   string    `json:"holdToken"`
+		GapWarnings []SeatGap `json:"gapWarnings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&hold); err != nil || hold.HoldToken == "" {
+		return "", nil, fmt.Errorf("failed to reserve seats")
+	}
+	return hold.HoldToken, hold.GapWarnings, nil
+}
+
+// ErrHoldGone is returned when cinema service no longer has a seat hold,
+// because it expired or was released.
+var ErrHoldGone = errors.New("seat hold expired or was released")
+
+// ConfirmHold turns a hold into a sale. Confirming a hold again succeeds,
+// so failures that are not ErrHoldGone may be retried.
+func ConfirmHold(holdToken string) error {
+	reqBody := map[string]string{"holdToken": holdToken}
+	resp, err := postCinema("/api/cinema/seats/hold/confirm", reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to reserve seats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound, http.StatusGone:
+		return ErrHoldGone
+	}
+	return fmt.Errorf("failed to reserve seats: cinema service answered %s", resp.Status)
+}
+
+// ReleaseHold gives held seats back before the hold expires. Failures are
+// logged; cinema service releases the seats by itself once the hold
+// expires.
+func ReleaseHold(holdToken string) {
+	reqBody := map[string]string{"holdToken": holdToken}
+	if err := postRelease("/api/cinema/seats/hold/release", reqBody); err != nil {
+		log.Printf("Failed to release seat hold: %v", err)
+	}
+}
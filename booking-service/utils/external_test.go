@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withCinemaService points the cinema service client at handler for the
+// duration of a test.
+func withCinemaService(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	previous := cinemaServiceURL
+	cinemaServiceURL = server.URL
+	t.Cleanup(func() {
+		cinemaServiceURL = previous
+		server.Close()
+	})
+}
+
+func TestSeatRequestsCarryServiceToken(t *testing.T) {
+	previous := serviceToken
+	serviceToken = "secret"
+	defer func() { serviceToken = previous }()
+
+	var tokens []string
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("X-Service-Token"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	HoldSeats(1, 1, []uint{1})
+	ConfirmHold("h1")
+	ReleaseHold("h1")
+	ReleaseSeats(1, []uint{1})
+	assert.Equal(t, []string{"secret", "secret", "secret", "secret"}, tokens)
+}
+
+func TestHoldSeatsConflict(t *testing.T) {
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/cinema/seats/hold", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"some seats are not available","conflicts":[{"seatId":2,"seatNumber":"A2","status":"sold"}]}`))
+	})
+
+	token, _, err := HoldSeats(1, 1, []uint{1, 2})
+	assert.Empty(t, token)
+
+	var conflict *SeatConflictError
+	if assert.True(t, errors.As(err, &conflict)) {
+		assert.Equal(t, "some seats are not available", conflict.Error())
+		assert.Equal(t, []SeatConflict{{SeatID: 2, SeatNumber: "A2", Status: "sold"}}, conflict.Seats)
+	}
+}
+
+func TestHoldSeatsResponses(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		expectError    string
+		expectConflict bool
+	}{
+		{
+			name:   "Held",
+			status: http.StatusCreated,
+			body:   `{"holdToken":"h1"}`,
+		},
+		{
+			name:           "Seats taken",
+			status:         http.StatusConflict,
+			body:           `{"error":"some seats are not available","conflicts":[{"seatId":5,"status":"held"}]}`,
+			expectError:    "some seats are not available",
+			expectConflict: true,
+		},
+		{
+			name:        "Seat of another studio",
+			status:      http.StatusBadRequest,
+			body:        `{"error":"some seats do not belong to the showtime's studio","seatIds":[5]}`,
+			expectError: "some seats do not belong to the showtime's studio",
+		},
+		{
+			name:        "Cinema service failure",
+			status:      http.StatusInternalServerError,
+			body:        `{"error":"Failed to reserve seats"}`,
+			expectError: "failed to reserve seats",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, _, err := HoldSeats(1, 1, []uint{5})
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.expectError)
+			var conflict *SeatConflictError
+			assert.Equal(t, tt.expectConflict, errors.As(err, &conflict))
+		})
+	}
+}
+
+func TestHoldSeatsGapWarnings(t *testing.T) {
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"holdToken":"h1","gapWarnings":[{"seatId":3,"seatNumber":"A3","row":"A"}]}`))
+	})
+
+	token, gaps, err := HoldSeats(1, 1, []uint{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "h1", token)
+	assert.Equal(t, []SeatGap{{SeatID: 3, SeatNumber: "A3", Row: "A"}}, gaps)
+}
+
+func TestHoldSeatsGapRejected(t *testing.T) {
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"selection would leave a single empty seat","gaps":[{"seatId":3,"seatNumber":"A3","row":"A"}]}`))
+	})
+
+	_, _, err := HoldSeats(1, 1, []uint{1, 2})
+
+	var conflict *SeatConflictError
+	if assert.True(t, errors.As(err, &conflict)) {
+		assert.Equal(t, "selection would leave a single empty seat", conflict.Error())
+		assert.Empty(t, conflict.Seats)
+		assert.Equal(t, []SeatGap{{SeatID: 3, SeatNumber: "A3", Row: "A"}}, conflict.Gaps)
+	}
+}
+
+func TestHoldSeatsRejectedRequest(t *testing.T) {
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"seatIds lists the same seat more than once","seatIds":[4]}`))
+	})
+
+	_, _, err := HoldSeats(1, 1, []uint{4, 4})
+
+	var invalid *SeatRequestError
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, "seatIds lists the same seat more than once", invalid.Error())
+		assert.Equal(t, []uint{4}, invalid.SeatIDs)
+	}
+}
+
+func TestGetShowtimeAndSeats(t *testing.T) {
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/cinema/showtimes/4":
+			w.Write([]byte(`{"id":4,"studio_id":2,"start_time":"2024-05-01T19:00:00Z","format":"3D"}`))
+		case "/api/cinema/studios/2/seats":
+			assert.Equal(t, "4", r.URL.Query().Get("showtime_id"))
+			w.Write([]byte(`[{"id":7,"seat_number":"B3","seat_type":"premium"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"showtime not found"}`))
+		}
+	})
+
+	showtime, err := GetShowtime(4)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint(2), showtime.StudioID)
+		assert.Equal(t, "3D", showtime.Format)
+	}
+
+	seats, err := GetStudioSeats(2, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []Seat{{ID: 7, SeatNumber: "B3", SeatType: "premium"}}, seats)
+
+	_, err = GetShowtime(5)
+	var invalid *SeatRequestError
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, "showtime not found", invalid.Error())
+	}
+}
+
+func TestGetShowtimes(t *testing.T) {
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("date") != "2024-05-01" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"date must be YYYY-MM-DD"}`))
+			return
+		}
+		assert.Equal(t, "2", r.URL.Query().Get("studio_id"))
+		w.Write([]byte(`[{"id":4,"studio_id":2},{"id":6,"studio_id":2}]`))
+	})
+
+	showtimes, err := GetShowtimes("2024-05-01", 2)
+	if assert.NoError(t, err) && assert.Len(t, showtimes, 2) {
+		assert.Equal(t, uint(6), showtimes[1].ID)
+	}
+
+	_, err = GetShowtimes("May 1st", 2)
+	var invalid *SeatRequestError
+	if assert.True(t, errors.As(err, &invalid)) {
+		assert.Equal(t, "date must be YYYY-MM-DD", invalid.Error())
+	}
+}
+
+func TestReleaseFailures(t *testing.T) {
+	requests := 0
+	withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Failed to release seats"}`))
+	})
+
+	assert.EqualError(t, postRelease("/api/cinema/seats/release", map[string]string{}), "cinema service answered 500 Internal Server Error")
+	ReleaseSeats(1, []uint{5})
+	ReleaseHold("h1")
+	assert.Equal(t, 3, requests)
+}
+
+func TestConfirmHoldResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantGone bool
+		wantErr  bool
+	}{
+		{"Confirmed", http.StatusOK, false, false},
+		{"Hold expired", http.StatusGone, true, true},
+		{"Hold unknown", http.StatusNotFound, true, true},
+		{"Cinema service failing", http.StatusInternalServerError, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withCinemaService(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/cinema/seats/hold/confirm", r.URL.Path)
+				w.WriteHeader(tt.status)
+			})
+
+			err := ConfirmHold("h1")
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantGone, errors.Is(err, ErrHoldGone))
+		})
+	}
+}
@@ -2,32 +2,29 @@ package utils
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"time"
 
 	"github.com/skip2/go-qrcode"
 )
 
-func GenerateQRCode(bookingCode string, studioID uint, seatIDs []uint, userID *uint, customerName string) (string, error) {
-	qrData := map[string]interface{}{
-		"bookingCode": bookingCode,
-		"studioId":    studioID,
-		"seatIds":     seatIDs,
-		"timestamp":   time.Now().Format(time.RFC3339),
+// GenerateQRCode signs a ticket for a booking and draws it as a PNG QR
+// code data URL. Scanners send the text of the QR code back to be
+// validated.
+func GenerateQRCode(bookingCode string, studioID, showtimeID uint, seatIDs []uint, userID *uint, customerName string) (string, error) {
+	ticket := Ticket{
+		BookingCode: bookingCode,
+		StudioID:    studioID,
+		ShowtimeID:  showtimeID,
+		SeatIDs:     seatIDs,
+		IssuedAt:    time.Now(),
 	}
-	
 	if userID != nil {
-		qrData["userId"] = *userID
+		ticket.UserID = userID
 	} else {
-		qrData["customerName"] = customerName
+		ticket.CustomerName = customerName
 	}
 
-	qrDataJSON, err := json.Marshal(qrData)
-	if err != nil {
-		return "", err
-	}
-
-	qrCodeBytes, err := qrcode.Encode(string(qrDataJSON), qrcode.Medium, 256)
+	qrCodeBytes, err := qrcode.Encode(SignTicket(ticket), qrcode.Medium, 256)
 	if err != nil {
 		return "", err
 	}
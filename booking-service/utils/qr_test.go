@@ -13,6 +13,7 @@ func TestGenerateQRCode(t *testing.T) {
 		name         string
 		bookingCode  string
 		studioID     uint
+		showtimeID   uint
 		seatIDs      []uint
 		userID       *uint
 		customerName string
@@ -22,6 +23,7 @@ func TestGenerateQRCode(t *testing.T) {
 			name:        "Valid QR code with user ID",
 			bookingCode: "BOOK123",
 			studioID:    1,
+			showtimeID:  1,
 			seatIDs:     []uint{1, 2, 3},
 			userID:      func() *uint { id := uint(1); return &id }(),
 			expectError: false,
@@ -30,6 +32,7 @@ func TestGenerateQRCode(t *testing.T) {
 			name:         "Valid QR code with customer name",
 			bookingCode:  "BOOK456",
 			studioID:     2,
+			showtimeID:   1,
 			seatIDs:      []uint{4, 5},
 			customerName: "John Doe",
 			expectError:  false,
@@ -38,6 +41,7 @@ func TestGenerateQRCode(t *testing.T) {
 			name:        "Empty booking code",
 			bookingCode: "",
 			studioID:    1,
+			showtimeID:  1,
 			seatIDs:     []uint{1},
 			userID:      func() *uint { id := uint(1); return &id }(),
 			expectError: false, // QR generation should still work
@@ -46,6 +50,7 @@ func TestGenerateQRCode(t *testing.T) {
 			name:        "Empty seat IDs",
 			bookingCode: "BOOK789",
 			studioID:    1,
+			showtimeID:  1,
 			seatIDs:     []uint{},
 			userID:      func() *uint { id := uint(1); return &id }(),
 			expectError: false,
@@ -54,7 +59,7 @@ func TestGenerateQRCode(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			qrCode, err := GenerateQRCode(tt.bookingCode, tt.studioID, tt.seatIDs, tt.userID, tt.customerName)
+			qrCode, err := GenerateQRCode(tt.bookingCode, tt.studioID, tt.showtimeID, tt.seatIDs, tt.userID, tt.customerName)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -78,17 +83,18 @@ func TestGenerateQRCode(t *testing.T) {
 func TestQRCodeDataStructure(t *testing.T) {
 	bookingCode := "TEST123"
 	studioID := uint(1)
+	showtimeID := uint(1)
 	seatIDs := []uint{1, 2, 3}
 	userID := uint(1)
 	customerName := "Test User"
 
 	// Test with user ID
-	qrCode, err := GenerateQRCode(bookingCode, studioID, seatIDs, &userID, "")
+	qrCode, err := GenerateQRCode(bookingCode, studioID, showtimeID, seatIDs, &userID, "")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, qrCode)
 
 	// Test with customer name
-	qrCode2, err := GenerateQRCode(bookingCode, studioID, seatIDs, nil, customerName)
+	qrCode2, err := GenerateQRCode(bookingCode, studioID, showtimeID, seatIDs, nil, customerName)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, qrCode2)
 
@@ -99,12 +105,13 @@ func TestQRCodeDataStructure(t *testing.T) {
 func TestQRCodeConsistency(t *testing.T) {
 	bookingCode := "CONSISTENT123"
 	studioID := uint(1)
+	showtimeID := uint(1)
 	seatIDs := []uint{1, 2, 3}
 	userID := uint(1)
 
 	// Generate QR code twice with same inputs
-	qrCode1, err1 := GenerateQRCode(bookingCode, studioID, seatIDs, &userID, "")
-	qrCode2, err2 := GenerateQRCode(bookingCode, studioID, seatIDs, &userID, "")
+	qrCode1, err1 := GenerateQRCode(bookingCode, studioID, showtimeID, seatIDs, &userID, "")
+	qrCode2, err2 := GenerateQRCode(bookingCode, studioID, showtimeID, seatIDs, &userID, "")
 
 	assert.NoError(t, err1)
 	assert.NoError(t, err2)
@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ticketVersion prefixes every signed ticket so the encoding can change
+// without old tickets being misread.
+const ticketVersion = "T1"
+
+var (
+	ErrMalformedTicket  = errors.New("ticket is not a valid cinema ticket")
+	ErrUnknownTicketKey = errors.New("ticket was signed with an unknown key")
+	ErrTicketSignature  = errors.New("ticket signature does not match")
+)
+
+var ticketKeyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Ticket is what a QR code says about a booking. Online tickets carry the
+// customer's user ID and offline tickets their name.
+type Ticket struct {
+	BookingCode  string
+	StudioID     uint
+	ShowtimeID   uint
+	SeatIDs      []uint
+	UserID       *uint
+	CustomerName string
+	IssuedAt     time.Time
+}
+
+// TicketKeys signs tickets with one Ed25519 key and accepts tickets signed
+// with any key it knows. Keys are rotated by signing with a new key while
+// still accepting the previous one until its tickets have been used.
+type TicketKeys struct {
+	activeID string
+	active   ed25519.PrivateKey
+	public   map[string]ed25519.PublicKey
+}
+
+// NewTicketKeys returns keys that sign with key under the ID id.
+func NewTicketKeys(id string, key ed25519.PrivateKey) (*TicketKeys, error) {
+	if !ticketKeyIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid ticket key ID %q", id)
+	}
+	return &TicketKeys{
+		activeID: id,
+		active:   key,
+		public:   map[string]ed25519.PublicKey{id: key.Public().(ed25519.PublicKey)},
+	}, nil
+}
+
+// Accept makes tickets signed by the private half of key valid too.
+func (k *TicketKeys) Accept(id string, key ed25519.PublicKey) error {
+	if !ticketKeyIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid ticket key ID %q", id)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("ticket key %q is not an Ed25519 public key", id)
+	}
+	k.public[id] = key
+	return nil
+}
+
+// ActiveKeyID is the ID of the key new tickets are signed with.
+func (k *TicketKeys) ActiveKeyID() string {
+	return k.activeID
+}
+
+// PublicKeys returns every key tickets are accepted from, by key ID.
+func (k *TicketKeys) PublicKeys() map[string]ed25519.PublicKey {
+	keys := make(map[string]ed25519.PublicKey, len(k.public))
+	for id, key := range k.public {
+		keys[id] = key
+	}
+	return keys
+}
+
+// Sign encodes a ticket as "T1.<key ID>.<payload>.<signature>", with the
+// payload and signature in unpadded base64url. The signature covers
+// everything before it.
+func (k *TicketKeys) Sign(ticket Ticket) string {
+	signed := ticketVersion + "." + k.activeID + "." + base64.RawURLEncoding.EncodeToString(encodeTicket(ticket))
+	signature := ed25519.Sign(k.active, []byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Verify checks a ticket's signature and decodes it. Nothing in a ticket
+// is read before its signature has been checked.
+func (k *TicketKeys) Verify(token string) (*Ticket, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 || parts[0] != ticketVersion {
+		return nil, ErrMalformedTicket
+	}
+	key, ok := k.public[parts[1]]
+	if !ok {
+		return nil, ErrUnknownTicketKey
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, ErrMalformedTicket
+	}
+	signed := token[:len(token)-len(parts[3])-1]
+	if !ed25519.Verify(key, []byte(signed), signature) {
+		return nil, ErrTicketSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedTicket
+	}
+	return decodeTicket(payload)
+}
+
+// encodeTicket packs a ticket into varints and length-prefixed strings:
+// issue time in Unix milliseconds, studio, showtime, user ID (0 for
+// offline tickets), booking code, customer name, then the seat count and
+// seat IDs.
+func encodeTicket(ticket Ticket) []byte {
+	var buf []byte
+	var userID uint64
+	if ticket.UserID != nil {
+		userID = uint64(*ticket.UserID)
+	}
+	buf = binary.AppendUvarint(buf, uint64(ticket.IssuedAt.UnixMilli()))
+	buf = binary.AppendUvarint(buf, uint64(ticket.StudioID))
+	buf = binary.AppendUvarint(buf, uint64(ticket.ShowtimeID))
+	buf = binary.AppendUvarint(buf, userID)
+	buf = binary.AppendUvarint(buf, uint64(len(ticket.BookingCode)))
+	buf = append(buf, ticket.BookingCode...)
+	buf = binary.AppendUvarint(buf, uint64(len(ticket.CustomerName)))
+	buf = append(buf, ticket.CustomerName...)
+	buf = binary.AppendUvarint(buf, uint64(len(ticket.SeatIDs)))
+	for _, id := range ticket.SeatIDs {
+		buf = binary.AppendUvarint(buf, uint64(id))
+	}
+	return buf
+}
+
+// ticketReader reads the fields of an encoded ticket, remembering the first
+// error so the caller only checks once.
+type ticketReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (t *ticketReader) uint() uint64 {
+	if t.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		t.err = ErrMalformedTicket
+	}
+	return v
+}
+
+func (t *ticketReader) string() string {
+	n := t.uint()
+	if t.err != nil {
+		return ""
+	}
+	if n > uint64(t.r.Len()) {
+		t.err = ErrMalformedTicket
+		return ""
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(t.r, buf); err != nil {
+		t.err = ErrMalformedTicket
+	}
+	return string(buf)
+}
+
+func decodeTicket(payload []byte) (*Ticket, error) {
+	r := &ticketReader{r: bytes.NewReader(payload)}
+	ticket := Ticket{IssuedAt: time.UnixMilli(int64(r.uint()))}
+	ticket.StudioID = uint(r.uint())
+	ticket.ShowtimeID = uint(r.uint())
+	if userID := uint(r.uint()); userID != 0 {
+		ticket.UserID = &userID
+	}
+	ticket.BookingCode = r.string()
+	ticket.CustomerName = r.string()
+
+	// Every seat takes at least one byte, which bounds the count.
+	count := r.uint()
+	if r.err == nil && count > uint64(r.r.Len()) {
+		return nil, ErrMalformedTicket
+	}
+	ticket.SeatIDs = make([]uint, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		ticket.SeatIDs = append(ticket.SeatIDs, uint(r.uint()))
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.r.Len() > 0 {
+		return nil, ErrMalformedTicket
+	}
+	return &ticket, nil
+}
+
+// TicketKeysFromEnv loads the signing key from QR_SIGNING_KEY, a base64
+// Ed25519 seed, under the ID in QR_SIGNING_KEY_ID (default "k1").
+// QR_PREVIOUS_KEYS lists retired keys that are still accepted as
+// comma-separated "<key ID>:<base64 public key>" pairs. Without a signing
+// key a temporary one is generated, so tickets stop validating when the
+// service restarts.
+func TicketKeysFromEnv() (*TicketKeys, error) {
+	id := os.Getenv("QR_SIGNING_KEY_ID")
+	if id == "" {
+		id = "k1"
+	}
+
+	var private ed25519.PrivateKey
+	if seed := os.Getenv("QR_SIGNING_KEY"); seed != "" {
+		raw, err := base64.StdEncoding.DecodeString(seed)
+		if err != nil || len(raw) != ed25519.SeedSize {
+			return nil, errors.New("QR_SIGNING_KEY must be a base64 encoded 32 byte Ed25519 seed")
+		}
+		private = ed25519.NewKeyFromSeed(raw)
+	} else {
+		log.Println("QR_SIGNING_KEY is not set; signing tickets with a temporary key")
+		_, generated, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = generated
+	}
+
+	keys, err := NewTicketKeys(id, private)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous := os.Getenv("QR_PREVIOUS_KEYS"); previous != "" {
+		for _, entry := range strings.Split(previous, ",") {
+			keyID, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok {
+				return nil, fmt.Errorf("QR_PREVIOUS_KEYS entry %q is not <key ID>:<public key>", entry)
+			}
+			public, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("QR_PREVIOUS_KEYS key %q is not base64", keyID)
+			}
+			if err := keys.Accept(keyID, ed25519.PublicKey(public)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return keys, nil
+}
+
+var (
+	ticketKeys     *TicketKeys
+	ticketKeysOnce sync.Once
+)
+
+// UseTicketKeys sets the keys tickets are signed and verified with.
+func UseTicketKeys(keys *TicketKeys) {
+	ticketKeys = keys
+}
+
+// currentTicketKeys returns the keys in use, falling back to a temporary
+// key when none were set, as in tests.
+func currentTicketKeys() *TicketKeys {
+	ticketKeysOnce.Do(func() {
+		if ticketKeys != nil {
+			return
+		}
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		ticketKeys, _ = NewTicketKeys("dev", private)
+	})
+	return ticketKeys
+}
+
+// SignTicket signs a ticket with the active key.
+func SignTicket(ticket Ticket) string {
+	return currentTicketKeys().Sign(ticket)
+}
+
+// TicketPublicKeys returns every key tickets are accepted from, by key ID.
+func TicketPublicKeys() map[string]ed25519.PublicKey {
+	return currentTicketKeys().PublicKeys()
+}
+
+// VerifyTicket checks a scanned ticket and returns what it says.
+func VerifyTicket(token string) (*Ticket, error) {
+	return currentTicketKeys().Verify(token)
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTicketKeys(t *testing.T, id string) *TicketKeys {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewTicketKeys(id, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestTicketRoundTrip(t *testing.T) {
+	keys := newTestTicketKeys(t, "k1")
+	userID := uint(7)
+	issuedAt := time.UnixMilli(time.Now().UnixMilli())
+
+	tests := []struct {
+		name   string
+		ticket Ticket
+	}{
+		{
+			name: "online ticket",
+			ticket: Ticket{
+				BookingCode: "0b8f6c1e-3a52-4d7f-9a43-4f1d2b7c9e10",
+				StudioID:    1,
+				ShowtimeID:  42,
+				SeatIDs:     []uint{4, 5, 300},
+				UserID:      &userID,
+				IssuedAt:    issuedAt,
+			},
+		},
+		{
+			name: "offline ticket",
+			ticket: Ticket{
+				BookingCode:  "BOOK456",
+				StudioID:     2,
+				ShowtimeID:   1,
+				SeatIDs:      []uint{9},
+				CustomerName: "John Doe",
+				IssuedAt:     issuedAt,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := keys.Sign(tt.ticket)
+			assert.True(t, strings.HasPrefix(token, "T1.k1."))
+
+			ticket, err := keys.Verify(token)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ticket, *ticket)
+		})
+	}
+}
+
+func TestTicketVerifyRejectsForgeries(t *testing.T) {
+	keys := newTestTicketKeys(t, "k1")
+	token := keys.Sign(Ticket{BookingCode: "BOOK123", StudioID: 1, ShowtimeID: 1, SeatIDs: []uint{1}, IssuedAt: time.Now()})
+	parts := strings.Split(token, ".")
+
+	edited := encodeTicket(Ticket{BookingCode: "BOOK999", StudioID: 1, ShowtimeID: 1, SeatIDs: []uint{1, 2}, IssuedAt: time.Now()})
+	foreign := newTestTicketKeys(t, "k1").Sign(Ticket{BookingCode: "BOOK123"})
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"bare booking code", "BOOK123", ErrMalformedTicket},
+		{"unknown version", "T2" + token[2:], ErrMalformedTicket},
+		{"unknown key", "T1.k2." + parts[2] + "." + parts[3], ErrUnknownTicketKey},
+		{"edited payload", "T1.k1." + base64.RawURLEncoding.EncodeToString(edited) + "." + parts[3], ErrTicketSignature},
+		{"signed by another service", foreign, ErrTicketSignature},
+		{"signature not base64", "T1.k1." + parts[2] + ".!!", ErrMalformedTicket},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket, err := keys.Verify(tt.token)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Nil(t, ticket)
+		})
+	}
+}
+
+func TestTicketKeyRotation(t *testing.T) {
+	old := newTestTicketKeys(t, "k1")
+	current := newTestTicketKeys(t, "k2")
+	assert.NoError(t, current.Accept("k1", old.PublicKeys()["k1"]))
+
+	oldTicket := old.Sign(Ticket{BookingCode: "OLD", IssuedAt: time.Now()})
+	newTicket := current.Sign(Ticket{BookingCode: "NEW", IssuedAt: time.Now()})
+
+	ticket, err := current.Verify(oldTicket)
+	assert.NoError(t, err)
+	assert.Equal(t, "OLD", ticket.BookingCode)
+
+	_, err = old.Verify(newTicket)
+	assert.ErrorIs(t, err, ErrUnknownTicketKey)
+
+	assert.Equal(t, "k2", current.ActiveKeyID())
+	assert.Len(t, current.PublicKeys(), 2)
+	assert.Error(t, current.Accept("bad.id", old.PublicKeys()["k1"]))
+}
+
+func TestDecodeTicketRejectsBadPayloads(t *testing.T) {
+	valid := encodeTicket(Ticket{BookingCode: "BOOK123", SeatIDs: []uint{1, 2}, IssuedAt: time.Now()})
+
+	for name, payload := range map[string][]byte{
+		"empty":          {},
+		"truncated":      valid[:len(valid)-1],
+		"trailing bytes": append(append([]byte{}, valid...), 0),
+		"huge string":    {1, 1, 1, 0, 0xff, 0xff, 0x03},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeTicket(payload)
+			assert.ErrorIs(t, err, ErrMalformedTicket)
+		})
+	}
+}
+
+func TestTicketKeysFromEnv(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 1
+	previous := newTestTicketKeys(t, "old")
+
+	t.Setenv("QR_SIGNING_KEY_ID", "2024-06")
+	t.Setenv("QR_SIGNING_KEY", base64.StdEncoding.EncodeToString(seed))
+	t.Setenv("QR_PREVIOUS_KEYS", "old:"+base64.StdEncoding.EncodeToString(previous.PublicKeys()["old"]))
+
+	keys, err := TicketKeysFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06", keys.ActiveKeyID())
+	assert.Equal(t, ed25519.NewKeyFromSeed(seed).Public(), keys.PublicKeys()["2024-06"])
+
+	_, err = keys.Verify(previous.Sign(Ticket{BookingCode: "BOOK123"}))
+	assert.NoError(t, err)
+
+	t.Setenv("QR_SIGNING_KEY", "too-short")
+	_, err = TicketKeysFromEnv()
+	assert.Error(t, err)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"cinema-service/models"
 	"gorm.io/driver/postgres"
@@ -25,7 +28,7 @@ func Init() {
 	}
 
 	// Auto-migrate the schema
-	err = DB.AutoMigrate(&models.Studio{}, &models.Seat{})
+	err = DB.AutoMigrate(&models.Studio{}, &models.Seat{}, &models.Movie{}, &models.Showtime{}, &models.SeatInventory{}, &models.Aisle{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -34,6 +37,7 @@ func Init() {
 	DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_studio_seat ON seats(studio_id, seat_number)")
 
 	initializeData()
+	backfillSeatGeometry()
 	log.Println("Cinema database initialized with GORM")
 }
 
@@ -43,21 +47,62 @@ func initializeData() {
 
 	if count == 0 {
 		for i := 1; i <= 5; i++ {
+			// One row of 20 seats split by a centre aisle at column 11
 			studio := models.Studio{
 				Name:       fmt.Sprintf("Studio %d", i),
 				TotalSeats: 20,
+				Rows:       1,
+				Columns:    21,
 			}
 			DB.Create(&studio)
+			DB.Create(&models.Aisle{StudioID: studio.ID, Column: 11})
 
 			for j := 1; j <= 20; j++ {
+				column := j
+				if j > 10 {
+					column++
+				}
 				seat := models.Seat{
 					StudioID:    studio.ID,
 					SeatNumber:  fmt.Sprintf("A%d", j),
-					IsAvailable: true,
+					RowLabel:    "A",
+					RowIndex:    1,
+					ColumnIndex: column,
+					SeatType:    models.SeatTypeStandard,
 				}
 				DB.Create(&seat)
 			}
 		}
 		log.Println("Initialized 5 studios with 20 seats each")
 	}
-}
\ No newline at end of file
+}
+
+// backfillSeatGeometry places seats created before studios had a layout,
+// reading the row letter and the column from seat numbers such as "A5".
+func backfillSeatGeometry() {
+	var seats []models.Seat
+	DB.Where("row_index = 0 OR row_index IS NULL").Find(&seats)
+
+	for _, seat := range seats {
+		label := strings.TrimRightFunc(seat.SeatNumber, unicode.IsDigit)
+		column, err := strconv.Atoi(strings.TrimPrefix(seat.SeatNumber, label))
+		if label == "" || err != nil {
+			continue
+		}
+
+		rowIndex := 0
+		for _, ch := range strings.ToUpper(label) {
+			rowIndex = rowIndex*26 + int(ch-'A'+1)
+		}
+
+		DB.Model(&models.Seat{}).Where("id = ?", seat.ID).Updates(map[string]interface{}{
+			"row_label":    label,
+			"row_index":    rowIndex,
+			"column_index": column,
+			"seat_type":    models.SeatTypeStandard,
+		})
+	}
+	if len(seats) > 0 {
+		log.Printf("Backfilled layout position of %d seats", len(seats))
+	}
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"cinema-service/models"
 	"cinema-service/services"
+	"cinema-service/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,11 +22,25 @@ func GetStudios(c *gin.Context) {
 }
 
 func GetStudioSeats(c *gin.Context) {
-	studioID := c.Param("id")
+	studioID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+	showtimeID, ok := parseUintQuery(c, "showtime_id")
+	if !ok {
+		return
+	}
 
-	seats, err := services.GetStudioSeats(studioID)
+	seats, err := services.GetStudioSeats(studioID, showtimeID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seats"})
+		switch {
+		case errors.Is(err, services.ErrShowtimeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrShowtimeStudioMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seats"})
+		}
 		return
 	}
 
@@ -38,17 +54,51 @@ func ReserveSeats(c *gin.Context) {
 		return
 	}
 
-	err := services.ReserveSeats(req.SeatIDs)
+	gaps, err := services.ReserveSeats(req)
 	if err != nil {
-		if err.Error() == "some seats are not available" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve seats"})
+		respondSeatError(c, err, "Failed to reserve seats")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Seats reserved successfully"})
+	response := gin.H{"message": "Seats reserved successfully"}
+	if len(gaps) > 0 {
+		response["gapWarnings"] = gaps
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// respondSeatError maps errors from reserving or holding seats to HTTP
+// statuses.
+func respondSeatError(c *gin.Context, err error, fallback string) {
+	var selection *services.SeatSelectionError
+	var gap *services.SeatGapError
+	switch {
+	case errors.As(err, &selection):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "seatIds": selection.SeatIDs})
+	case errors.As(err, &gap):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "gaps": gap.Seats})
+	case errors.Is(err, services.ErrShowtimeRequired),
+		errors.Is(err, services.ErrNoSeatsRequested),
+		errors.Is(err, services.ErrShowtimeStudioMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrShowtimeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSeatsUnavailable):
+		c.JSON(http.StatusConflict, seatConflictResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// seatConflictResponse describes a failed seat claim, listing each seat
+// that was not available together with its current state.
+func seatConflictResponse(err error) gin.H {
+	response := gin.H{"error": err.Error(), "conflicts": []models.SeatConflict{}}
+	var conflict *services.SeatConflictError
+	if errors.As(err, &conflict) {
+		response["conflicts"] = conflict.Seats
+	}
+	return response
 }
 
 func ReleaseSeats(c *gin.Context) {
@@ -58,7 +108,12 @@ func ReleaseSeats(c *gin.Context) {
 		return
 	}
 
-	err := services.ReleaseSeats(req.SeatIDs)
+	if req.ShowtimeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrShowtimeRequired.Error()})
+		return
+	}
+
+	err := services.ReleaseSeats(req.ShowtimeID, req.SeatIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release seats"})
 		return
@@ -66,3 +121,94 @@ func ReleaseSeats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Seats released successfully"})
 }
+
+func GetStudioLayout(c *gin.Context) {
+	studioID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+	showtimeID, ok := parseUintQuery(c, "showtime_id")
+	if !ok {
+		return
+	}
+
+	layout, err := services.GetStudioLayout(studioID, showtimeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrStudioNotFound),
+			errors.Is(err, services.ErrShowtimeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrShowtimeStudioMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch layout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, layout)
+}
+
+var layoutContentTypes = map[string]string{
+	utils.LayoutFormatText: "text/plain; charset=utf-8",
+	utils.LayoutFormatJSON: "application/json; charset=utf-8",
+	utils.LayoutFormatYAML: "application/yaml; charset=utf-8",
+}
+
+func ExportStudioLayout(c *gin.Context) {
+	studioID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+	format, err := utils.NormalizeLayoutFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	layout, err := services.GetStudioLayout(studioID, 0)
+	if err != nil {
+		if errors.Is(err, services.ErrStudioNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch layout"})
+		return
+	}
+
+	data, err := utils.ExportLayout(*layout, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export layout"})
+		return
+	}
+
+	c.Data(http.StatusOK, layoutContentTypes[format], data)
+}
+
+func SuggestSeats(c *gin.Context) {
+	showtimeID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+	count, ok := parseUintQuery(c, "count")
+	if !ok {
+		return
+	}
+
+	suggestions, err := services.SuggestSeats(showtimeID, int(count), c.Query("seat_type"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSeatCount),
+			errors.Is(err, services.ErrInvalidSeatType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrShowtimeNotFound),
+			errors.Is(err, services.ErrNoSeatBlock):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suggest seats"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"showtimeId": showtimeID, "count": count, "suggestions": suggestions})
+}
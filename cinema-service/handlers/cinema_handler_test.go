@@ -60,16 +60,36 @@ func TestReserveSeatsHandler(t *testing.T) {
 		{
 			name: "Valid seat reservation",
 			requestBody: models.SeatReservationRequest{
-				SeatIDs: []uint{1, 2, 3},
+				ShowtimeID: 1,
+				SeatIDs:    []uint{1, 2, 3},
 			},
 			expectedStatus: http.StatusInternalServerError, // No DB connection
 		},
 		{
 			name: "Empty seat IDs",
 			requestBody: models.SeatReservationRequest{
-				SeatIDs: []uint{},
+				ShowtimeID: 1,
+				SeatIDs:    []uint{},
 			},
-			expectedStatus: http.StatusInternalServerError, // No DB connection
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "seatIds must contain at least one seat",
+		},
+		{
+			name: "Duplicate seat IDs",
+			requestBody: models.SeatReservationRequest{
+				ShowtimeID: 1,
+				SeatIDs:    []uint{1, 2, 1},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "seatIds lists the same seat more than once",
+		},
+		{
+			name: "Missing showtime ID",
+			requestBody: models.SeatReservationRequest{
+				SeatIDs: []uint{1, 2, 3},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "showtimeId is required",
 		},
 	}
 
@@ -86,6 +106,11 @@ func TestReserveSeatsHandler(t *testing.T) {
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedError != "" {
+				var response map[string]interface{}
+				json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, tt.expectedError, response["error"])
+			}
 		})
 	}
 }
@@ -101,17 +126,26 @@ func TestReleaseSeatsHandler(t *testing.T) {
 		{
 			name: "Valid seat release",
 			requestBody: models.SeatReservationRequest{
-				SeatIDs: []uint{1, 2, 3},
+				ShowtimeID: 1,
+				SeatIDs:    []uint{1, 2, 3},
 			},
 			expectedStatus: http.StatusInternalServerError, // No DB connection
 		},
 		{
 			name: "Empty seat IDs",
 			requestBody: models.SeatReservationRequest{
-				SeatIDs: []uint{},
+				ShowtimeID: 1,
+				SeatIDs:    []uint{},
 			},
 			expectedStatus: http.StatusInternalServerError, // No DB connection
 		},
+		{
+			name: "Missing showtime ID",
+			requestBody: models.SeatReservationRequest{
+				SeatIDs: []uint{1, 2, 3},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -149,4 +183,37 @@ func TestInvalidJSONRequest(t *testing.T) {
 	var response map[string]string
 	json.Unmarshal(w.Body.Bytes(), &response)
 	assert.Equal(t, "Invalid request", response["error"])
-}
\ No newline at end of file
+}
+
+func TestSuggestSeatsHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedError string
+	}{
+		{name: "Invalid showtime", path: "/showtimes/abc/seats/suggest?count=2", expectedError: "Invalid id"},
+		{name: "Invalid count", path: "/showtimes/1/seats/suggest?count=two", expectedError: "Invalid count"},
+		{name: "Missing count", path: "/showtimes/1/seats/suggest", expectedError: "count must be between 1 and 10"},
+		{name: "Too many seats", path: "/showtimes/1/seats/suggest?count=11", expectedError: "count must be between 1 and 10"},
+		{name: "Unknown seat type", path: "/showtimes/1/seats/suggest?count=2&seat_type=balcony", expectedError: "unknown seat type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/showtimes/:id/seats/suggest", SuggestSeats)
+
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cinema-service/models"
+	"cinema-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HoldSeats(c *gin.Context) {
+	var req models.SeatHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	hold, err := services.HoldSeats(req)
+	if err != nil {
+		respondHoldError(c, err, "Failed to hold seats")
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+func ConfirmHold(c *gin.Context) {
+	var req models.HoldTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := services.ConfirmHold(req.HoldToken); err != nil {
+		respondHoldError(c, err, "Failed to confirm hold")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Seats reserved successfully"})
+}
+
+func ReleaseHold(c *gin.Context) {
+	var req models.HoldTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := services.ReleaseHold(req.HoldToken); err != nil {
+		respondHoldError(c, err, "Failed to release hold")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hold released successfully"})
+}
+
+// respondHoldError maps seat hold errors to HTTP statuses.
+func respondHoldError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrHoldTokenRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrHoldNotFound):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		respondSeatError(c, err, fallback)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoldHandlersValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		handler        gin.HandlerFunc
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Hold with invalid JSON",
+			handler:        HoldSeats,
+			body:           "invalid-json",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid request",
+		},
+		{
+			name:           "Hold without showtime",
+			handler:        HoldSeats,
+			body:           `{"seatIds": [1, 2]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "showtimeId is required",
+		},
+		{
+			name:           "Confirm without token",
+			handler:        ConfirmHold,
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "holdToken is required",
+		},
+		{
+			name:           "Release without token",
+			handler:        ReleaseHold,
+			body:           `{"holdToken": ""}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "holdToken is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/hold", tt.handler)
+
+			req, _ := http.NewRequest("POST", "/hold", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cinema-service/models"
+	"cinema-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseUintParam reads a numeric path parameter, answering 400 when it is
+// missing or malformed.
+func parseUintParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseUintQuery reads an optional numeric query parameter; absent means 0.
+func parseUintQuery(c *gin.Context, name string) (uint, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func GetMovies(c *gin.Context) {
+	movies, err := services.GetAllMovies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch movies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, movies)
+}
+
+func GetMovie(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	movie, err := services.GetMovie(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, movie)
+}
+
+func CreateMovie(c *gin.Context) {
+	var req models.MovieRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	movie, err := services.CreateMovie(req)
+	if err != nil {
+		respondMovieError(c, err, "Failed to create movie")
+		return
+	}
+
+	c.JSON(http.StatusCreated, movie)
+}
+
+func UpdateMovie(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.MovieRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	movie, err := services.UpdateMovie(id, req)
+	if err != nil {
+		respondMovieError(c, err, "Failed to update movie")
+		return
+	}
+
+	c.JSON(http.StatusOK, movie)
+}
+
+func DeleteMovie(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteMovie(id); err != nil {
+		respondMovieError(c, err, "Failed to delete movie")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Movie deleted successfully"})
+}
+
+func GetShowtimes(c *gin.Context) {
+	movieID, ok := parseUintQuery(c, "movie_id")
+	if !ok {
+		return
+	}
+	studioID, ok := parseUintQuery(c, "studio_id")
+	if !ok {
+		return
+	}
+
+	showtimes, err := services.GetShowtimes(c.Query("date"), movieID, studioID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch showtimes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, showtimes)
+}
+
+func GetShowtime(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	showtime, err := services.GetShowtime(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, showtime)
+}
+
+func CreateShowtime(c *gin.Context) {
+	var req models.ShowtimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	showtime, err := services.CreateShowtime(req)
+	if err != nil {
+		respondMovieError(c, err, "Failed to create showtime")
+		return
+	}
+
+	c.JSON(http.StatusCreated, showtime)
+}
+
+func UpdateShowtime(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ShowtimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	showtime, err := services.UpdateShowtime(id, req)
+	if err != nil {
+		respondMovieError(c, err, "Failed to update showtime")
+		return
+	}
+
+	c.JSON(http.StatusOK, showtime)
+}
+
+func DeleteShowtime(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteShowtime(id); err != nil {
+		respondMovieError(c, err, "Failed to delete showtime")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Showtime deleted successfully"})
+}
+
+// respondMovieError maps catalog and scheduling errors to HTTP statuses.
+func respondMovieError(c *gin.Context, err error, fallback string) {
+	var conflict *services.ShowtimeConflictError
+	switch {
+	case errors.As(err, &conflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflictingShowtimeId": conflict.Conflict.ID})
+	case errors.Is(err, services.ErrInvalidMovie),
+		errors.Is(err, services.ErrInvalidShowtime),
+		errors.Is(err, services.ErrInvalidFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMovieNotFound),
+		errors.Is(err, services.ErrStudioNotFound),
+		errors.Is(err, services.ErrShowtimeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMovieHasShowtimes),
+		errors.Is(err, services.ErrShowtimeHasSales):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
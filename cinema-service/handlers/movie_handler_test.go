@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovieHandlersInvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		method  string
+		route   string
+		path    string
+		handler gin.HandlerFunc
+	}{
+		{"Get movie", "GET", "/movies/:id", "/movies/abc", GetMovie},
+		{"Update movie", "PUT", "/movies/:id", "/movies/0", UpdateMovie},
+		{"Delete movie", "DELETE", "/movies/:id", "/movies/-1", DeleteMovie},
+		{"Get showtime", "GET", "/showtimes/:id", "/showtimes/abc", GetShowtime},
+		{"Update showtime", "PUT", "/showtimes/:id", "/showtimes/x", UpdateShowtime},
+		{"Delete showtime", "DELETE", "/showtimes/:id", "/showtimes/0", DeleteShowtime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Handle(tt.method, tt.route, tt.handler)
+
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, "Invalid id", response["error"])
+		})
+	}
+}
+
+func TestCreateMovieHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "Invalid JSON",
+			body:           "invalid-json",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid request",
+		},
+		{
+			name:           "Missing title",
+			body:           `{"runtimeMinutes": 120}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "title and a positive runtime are required",
+		},
+		{
+			name:           "Missing runtime",
+			body:           `{"title": "Dune"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "title and a positive runtime are required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/movies", CreateMovie)
+
+			req, _ := http.NewRequest("POST", "/movies", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response["error"])
+		})
+	}
+}
+
+func TestGetShowtimesInvalidFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/showtimes", GetShowtimes)
+
+	req, _ := http.NewRequest("GET", "/showtimes?studio_id=abc", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateShowtimeHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/showtimes", CreateShowtime)
+
+	body := `{"movieId": 1, "studioId": 1, "startTime": "2026-10-18T19:00:00Z", "format": "4DX"}`
+	req, _ := http.NewRequest("POST", "/showtimes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "format must be one of 2D, 3D or IMAX", response["error"])
+}
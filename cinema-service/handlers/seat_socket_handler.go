@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"cinema-service/models"
+	"cinema-service/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	seatSocketWriteWait  = 10 * time.Second
+	seatSocketPongWait   = 60 * time.Second
+	seatSocketPingPeriod = 50 * time.Second
+	seatSocketMaxMessage = 4096
+	// seatSocketMaxChanges is how many times a minute one connection may
+	// change its selection. Clients repeat their selection well within the
+	// selection TTL, so this leaves room for that and for real changes.
+	seatSocketMaxChanges = 30
+)
+
+var seatSocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkSeatSocketOrigin,
+}
+
+// checkSeatSocketOrigin lets browsers open the socket only from the origins
+// listed, comma separated, in ALLOWED_ORIGINS, or from the same host when
+// none are configured. Clients that send no Origin are not browsers and
+// cannot be lured into connecting by another site.
+func checkSeatSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	allowed := os.Getenv("ALLOWED_ORIGINS")
+	if allowed == "" {
+		return strings.EqualFold(strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://"), r.Host)
+	}
+	for _, candidate := range strings.Split(allowed, ",") {
+		if strings.EqualFold(strings.TrimSpace(candidate), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionLimiter counts the selection changes of one connection in the
+// current minute.
+type selectionLimiter struct {
+	windowStart time.Time
+	changes     int
+}
+
+// allow tells whether another selection change fits in the current minute
+// and counts it if it does.
+func (l *selectionLimiter) allow(now time.Time) bool {
+	if now.Sub(l.windowStart) >= time.Minute {
+		l.windowStart = now
+		l.changes = 0
+	}
+	if l.changes >= seatSocketMaxChanges {
+		return false
+	}
+	l.changes++
+	return true
+}
+
+// seatSocketRequest is a message from a seat selection client. "select"
+// replaces the client's tentative selection with SeatIDs; "clear" drops it.
+type seatSocketRequest struct {
+	Type    string `json:"type"`
+	SeatIDs []uint `json:"seatIds"`
+}
+
+// SeatSelectionSocket upgrades to a WebSocket on which a client shares its
+// tentative seat selection for a showtime and sees what everyone else is
+// selecting. The client receives:
+//
+//	snapshot    seat states and the seats others are selecting
+//	selected    the answer to its own select or clear
+//	selecting   seats someone else started selecting
+//	deselected  seats someone else stopped selecting
+//	seats       seats that were held, sold or released
+//	reset       it fell behind and should reconnect
+//	error       a message it sent could not be handled
+func SeatSelectionSocket(c *gin.Context) {
+	showtimeID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	showtime, err := services.GetShowtime(showtimeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	owner, err := services.NewSelectionOwner()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open seat selection"})
+		return
+	}
+
+	// Subscribe before loading the snapshot so no change falls in between.
+	seatChanges, unsubscribeSeats := services.SubscribeSeatEvents(showtimeID)
+	defer unsubscribeSeats()
+	selections, unsubscribeSelections := services.SubscribeSelectionEvents(showtimeID)
+	defer unsubscribeSelections()
+
+	seats, err := services.GetStudioSeats(showtime.StudioID, showtimeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seats"})
+		return
+	}
+
+	conn, err := seatSocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the client.
+		return
+	}
+	defer conn.Close()
+	defer services.ClearSelection(showtimeID, owner)
+
+	replies := make(chan gin.H, 8)
+	replies <- gin.H{
+		"type":       "snapshot",
+		"showtimeId": showtimeID,
+		"seats":      seatSnapshot(seats),
+		"selecting":  services.SelectedByOthers(showtimeID, owner),
+	}
+
+	quit := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		writeSeatSocket(conn, owner, replies, seatChanges, selections, quit)
+	}()
+
+	conn.SetReadLimit(seatSocketMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(seatSocketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(seatSocketPongWait))
+	})
+
+	limiter := &selectionLimiter{}
+	for {
+		var req seatSocketRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			break
+		}
+
+		select {
+		case replies <- handleSeatSocketRequest(showtimeID, owner, req, limiter):
+		case <-writerDone:
+		}
+	}
+
+	close(quit)
+	<-writerDone
+}
+
+// handleSeatSocketRequest applies one client message and returns the reply.
+// Selection changes beyond what limiter allows are refused.
+func handleSeatSocketRequest(showtimeID uint, owner string, req seatSocketRequest, limiter *selectionLimiter) gin.H {
+	if (req.Type == "select" || req.Type == "clear") && !limiter.allow(time.Now()) {
+		return gin.H{"type": "error", "error": "too many selection changes; slow down"}
+	}
+
+	switch req.Type {
+	case "select":
+		selection, err := services.SelectSeats(showtimeID, owner, req.SeatIDs)
+		if err != nil {
+			return gin.H{"type": "error", "error": err.Error()}
+		}
+		return selectionReply(selection)
+	case "clear":
+		services.ClearSelection(showtimeID, owner)
+		return selectionReply(models.SeatSelection{SeatIDs: []uint{}})
+	default:
+		return gin.H{"type": "error", "error": "unknown message type"}
+	}
+}
+
+func selectionReply(selection models.SeatSelection) gin.H {
+	reply := gin.H{"type": "selected", "seatIds": selection.SeatIDs, "rejected": selection.Rejected}
+	if !selection.ExpiresAt.IsZero() {
+		reply["expiresAt"] = selection.ExpiresAt
+	}
+	return reply
+}
+
+// writeSeatSocket is the only writer of conn. It sends replies, seat and
+// selection changes and keep-alive pings until quit is closed, a write
+// fails or a subscription is dropped.
+func writeSeatSocket(conn *websocket.Conn, owner string, replies <-chan gin.H, seatChanges <-chan models.SeatEvent, selections <-chan models.SeatSelectionEvent, quit <-chan struct{}) {
+	ping := time.NewTicker(seatSocketPingPeriod)
+	defer ping.Stop()
+	// Closing the connection also ends the reader when the writer stops
+	// first.
+	defer conn.Close()
+
+	for {
+		var message gin.H
+		select {
+		case <-quit:
+			return
+		case message = <-replies:
+		case event, ok := <-seatChanges:
+			if !ok {
+				message = gin.H{"type": "reset"}
+				break
+			}
+			message = gin.H{"type": "seats", "seatIds": event.SeatIDs, "status": event.Status, "at": event.At}
+		case event, ok := <-selections:
+			if !ok {
+				message = gin.H{"type": "reset"}
+				break
+			}
+			if event.Owner == owner {
+				continue
+			}
+			message = gin.H{"type": "deselected", "seatIds": event.SeatIDs}
+			if event.Selecting {
+				message["type"] = "selecting"
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(seatSocketWriteWait)); err != nil {
+				return
+			}
+			continue
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(seatSocketWriteWait))
+		if err := conn.WriteJSON(message); err != nil {
+			return
+		}
+		if message["type"] == "reset" {
+			return
+		}
+	}
+}
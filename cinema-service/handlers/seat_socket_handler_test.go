@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeatSelectionSocketInvalidShowtime(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/showtimes/:id/seats/ws", SeatSelectionSocket)
+
+	req, _ := http.NewRequest("GET", "/showtimes/abc/seats/ws", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Invalid id", response["error"])
+}
+
+func TestHandleSeatSocketRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       seatSocketRequest
+		wantType  string
+		wantError string
+	}{
+		{
+			name:     "select seats",
+			req:      seatSocketRequest{Type: "select", SeatIDs: []uint{1, 2}},
+			wantType: "selected",
+		},
+		{
+			name:      "too many seats",
+			req:       seatSocketRequest{Type: "select", SeatIDs: []uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+			wantType:  "error",
+			wantError: "at most 10 seats can be selected at once",
+		},
+		{
+			name:     "clear selection",
+			req:      seatSocketRequest{Type: "clear"},
+			wantType: "selected",
+		},
+		{
+			name:      "unknown message",
+			req:       seatSocketRequest{Type: "buy"},
+			wantType:  "error",
+			wantError: "unknown message type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := handleSeatSocketRequest(9001, "test-owner", tt.req, &selectionLimiter{})
+			assert.Equal(t, tt.wantType, reply["type"])
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantError, reply["error"])
+			}
+		})
+	}
+}
+
+func TestHandleSeatSocketRequestLimitsChanges(t *testing.T) {
+	limiter := &selectionLimiter{}
+	for i := 0; i < seatSocketMaxChanges; i++ {
+		reply := handleSeatSocketRequest(9002, "busy-owner", seatSocketRequest{Type: "clear"}, limiter)
+		assert.Equal(t, "selected", reply["type"])
+	}
+
+	reply := handleSeatSocketRequest(9002, "busy-owner", seatSocketRequest{Type: "select", SeatIDs: []uint{1}}, limiter)
+	assert.Equal(t, "error", reply["type"])
+	assert.Equal(t, "too many selection changes; slow down", reply["error"])
+
+	limiter.windowStart = limiter.windowStart.Add(-time.Minute)
+	reply = handleSeatSocketRequest(9002, "busy-owner", seatSocketRequest{Type: "clear"}, limiter)
+	assert.Equal(t, "selected", reply["type"])
+}
+
+func TestCheckSeatSocketOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		origin  string
+		want    bool
+	}{
+		{"no origin", "", "", true},
+		{"same host", "", "http://cinema.example", true},
+		{"other host", "", "http://evil.example", false},
+		{"listed origin", "http://localhost:4321, https://cinema.example", "https://cinema.example", true},
+		{"unlisted origin", "http://localhost:4321", "http://cinema.example", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOWED_ORIGINS", tt.allowed)
+
+			req, _ := http.NewRequest("GET", "/showtimes/1/seats/ws", nil)
+			req.Host = "cinema.example"
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			assert.Equal(t, tt.want, checkSeatSocketOrigin(req))
+		})
+	}
+}
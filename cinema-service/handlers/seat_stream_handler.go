@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"cinema-service/models"
+	"cinema-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// seatStreamKeepAlive is how often an idle seat stream sends a comment so
+// proxies and browsers keep the connection open.
+const seatStreamKeepAlive = 25 * time.Second
+
+// StreamSeatEvents keeps a Server-Sent Events stream open for a showtime.
+// It starts with a "snapshot" event holding the state of every seat, then
+// sends a "seats" event whenever seats are held, sold or released. A
+// "reset" event means the client fell behind and should reconnect.
+func StreamSeatEvents(c *gin.Context) {
+	showtimeID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	showtime, err := services.GetShowtime(showtimeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Subscribe before loading the snapshot so no change falls in between.
+	events, unsubscribe := services.SubscribeSeatEvents(showtimeID)
+	defer unsubscribe()
+
+	seats, err := services.GetStudioSeats(showtime.StudioID, showtimeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seats"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("snapshot", gin.H{"showtimeId": showtimeID, "seats": seatSnapshot(seats)})
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(seatStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				c.SSEvent("reset", gin.H{"showtimeId": showtimeID})
+				return false
+			}
+			c.SSEvent("seats", event)
+			return true
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		}
+	})
+}
+
+// seatSnapshot reduces seats to the state clients need to draw a seat map.
+func seatSnapshot(seats []models.Seat) []gin.H {
+	snapshot := make([]gin.H, len(seats))
+	for i, seat := range seats {
+		snapshot[i] = gin.H{"seatId": seat.ID, "status": seat.Status}
+	}
+	return snapshot
+}
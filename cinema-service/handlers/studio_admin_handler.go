@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"cinema-service/models"
+	"cinema-service/services"
+	"cinema-service/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CreateStudio(c *gin.Context) {
+	var req models.StudioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	studio, err := services.CreateStudio(req)
+	if err != nil {
+		respondStudioAdminError(c, err, "Failed to create studio")
+		return
+	}
+
+	c.JSON(http.StatusCreated, studio)
+}
+
+func UpdateStudio(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.StudioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	studio, err := services.UpdateStudio(id, req)
+	if err != nil {
+		respondStudioAdminError(c, err, "Failed to update studio")
+		return
+	}
+
+	c.JSON(http.StatusOK, studio)
+}
+
+func ReplaceStudioLayout(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.StudioLayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	studio, err := services.ReplaceStudioLayout(id, req)
+	if err != nil {
+		respondStudioAdminError(c, err, "Failed to replace layout")
+		return
+	}
+
+	c.JSON(http.StatusOK, studio)
+}
+
+func DeleteStudio(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteStudio(id); err != nil {
+		respondStudioAdminError(c, err, "Failed to delete studio")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Studio deleted successfully"})
+}
+
+func SetSeatMaintenance(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.SeatMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	seat, err := services.SetSeatMaintenance(id, req)
+	if err != nil {
+		respondStudioAdminError(c, err, "Failed to update seat")
+		return
+	}
+
+	c.JSON(http.StatusOK, seat)
+}
+
+// respondStudioAdminError maps studio administration errors to HTTP
+// statuses.
+func respondStudioAdminError(c *gin.Context, err error, fallback string) {
+	var invalid *services.LayoutValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid layout", "problems": invalid.Problems})
+	case errors.Is(err, services.ErrStudioNameRequired),
+		errors.Is(err, services.ErrInvalidGapRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrStudioNotFound),
+		errors.Is(err, services.ErrSeatNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrStudioHasShowtimes),
+		errors.Is(err, services.ErrSeatsInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// readLayoutDocument parses the request body as a layout document in the
+// format named by the format query parameter or, failing that, by the
+// request content type.
+func readLayoutDocument(c *gin.Context) (string, *models.StudioLayoutRequest, bool) {
+	source := c.Query("format")
+	if source == "" {
+		source = c.ContentType()
+	}
+	format, err := utils.NormalizeLayoutFormat(source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", nil, false
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return "", nil, false
+	}
+
+	name, layout, err := utils.ParseLayout(data, format)
+	if err != nil {
+		var invalid *utils.LayoutFormatError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid layout file", "problems": invalid.Problems})
+			return "", nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", nil, false
+	}
+	return name, layout, true
+}
+
+func ImportStudio(c *gin.Context) {
+	name, layout, ok := readLayoutDocument(c)
+	if !ok {
+		return
+	}
+
+	studio, err := services.CreateStudio(models.StudioRequest{Name: name, Layout: layout})
+	if err != nil {
+		respondStudioAdminError(c, err, "Failed to create studio")
+		return
+	}
+
+	c.JSON(http.StatusCreated, studio)
+}
+
+func ImportStudioLayout(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	_, layout, ok := readLayoutDocument(c)
+	if !ok {
+		return
+	}
+
+	studio, err := services.ReplaceStudioLayout(id, *layout)
+	if err != nil {
+		respondStudioAdminError(c, err, "Failed to replace layout")
+		return
+	}
+
+	c.JSON(http.StatusOK, studio)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateStudioHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/studios", CreateStudio)
+
+	req, _ := http.NewRequest("POST", "/studios", bytes.NewBufferString(`{"name": "  "}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "name is required", response["error"])
+}
+
+func TestStudioGapRuleValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{
+			name:   "Create with unknown gap rule",
+			method: "POST",
+			path:   "/studios",
+			body:   `{"name": "Studio 9", "gapRule": "sometimes"}`,
+		},
+		{
+			name:   "Update with unknown gap rule",
+			method: "PUT",
+			path:   "/studios/1",
+			body:   `{"name": "Studio 1", "gapRule": "strict"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/studios", CreateStudio)
+			router.PUT("/studios/:id", UpdateStudio)
+
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]string
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, "gapRule must be off, warn or reject", response["error"])
+		})
+	}
+}
+
+func TestReplaceStudioLayoutHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.PUT("/studios/:id/layout", ReplaceStudioLayout)
+
+	body := `{"aisleColumns": [2], "seats": [{"rowIndex": 1, "column": 2}]}`
+	req, _ := http.NewRequest("PUT", "/studios/1/layout", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Error    string   `json:"error"`
+		Problems []string `json:"problems"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Invalid layout", response.Error)
+	assert.Equal(t, []string{"seat at row 1, column 2: column is an aisle"}, response.Problems)
+}
+
+func TestImportStudioHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		path           string
+		contentType    string
+		body           string
+		expectedError  string
+		expectedErrors int
+	}{
+		{
+			name:          "Unsupported format",
+			path:          "/studios/import?format=xml",
+			body:          "<studio/>",
+			expectedError: `unsupported layout format "xml"`,
+		},
+		{
+			name:           "Unknown cell in text layout",
+			path:           "/studios/import",
+			contentType:    "text/plain",
+			body:           "name: Studio 9\nA SSQS\n",
+			expectedError:  "Invalid layout file",
+			expectedErrors: 1,
+		},
+		{
+			name:           "Malformed YAML layout",
+			path:           "/studios/import",
+			contentType:    "application/yaml",
+			body:           "rows: [unclosed",
+			expectedError:  "Invalid layout file",
+			expectedErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/studios/import", ImportStudio)
+
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response struct {
+				Error    string            `json:"error"`
+				Problems []json.RawMessage `json:"problems"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, tt.expectedError, response.Error)
+			assert.Len(t, response.Problems, tt.expectedErrors)
+		})
+	}
+}
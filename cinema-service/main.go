@@ -3,15 +3,20 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"cinema-service/database"
 	"cinema-service/handlers"
+	"cinema-service/middleware"
+	"cinema-service/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	database.Init()
+	services.StartHoldSweeper(time.Minute)
+	services.StartSelectionSweeper(5 * time.Second)
 
 	r := gin.Default()
 
@@ -19,8 +24,46 @@ func main() {
 	{
 		cinema.GET("/studios", handlers.GetStudios)
 		cinema.GET("/studios/:id/seats", handlers.GetStudioSeats)
-		cinema.POST("/seats/reserve", handlers.ReserveSeats)
-		cinema.POST("/seats/release", handlers.ReleaseSeats)
+		cinema.GET("/studios/:id/layout", handlers.GetStudioLayout)
+		cinema.GET("/studios/:id/layout/export", handlers.ExportStudioLayout)
+
+		cinema.GET("/movies", handlers.GetMovies)
+		cinema.GET("/movies/:id", handlers.GetMovie)
+
+		cinema.GET("/showtimes", handlers.GetShowtimes)
+		cinema.GET("/showtimes/:id", handlers.GetShowtime)
+		cinema.GET("/showtimes/:id/seats/suggest", handlers.SuggestSeats)
+		cinema.GET("/showtimes/:id/seats/events", handlers.StreamSeatEvents)
+		cinema.GET("/showtimes/:id/seats/ws", handlers.SeatSelectionSocket)
+	}
+
+	// Seats are only sold through booking service, which takes the payment.
+	seats := r.Group("/api/cinema/seats", middleware.RequireServiceToken())
+	{
+		seats.POST("/reserve", handlers.ReserveSeats)
+		seats.POST("/release", handlers.ReleaseSeats)
+		seats.POST("/hold", handlers.HoldSeats)
+		seats.POST("/hold/confirm", handlers.ConfirmHold)
+		seats.POST("/hold/release", handlers.ReleaseHold)
+	}
+
+	admin := r.Group("/api/cinema/admin", middleware.AuthMiddleware(), middleware.RequireRole("admin"))
+	{
+		admin.POST("/studios", handlers.CreateStudio)
+		admin.POST("/studios/import", handlers.ImportStudio)
+		admin.PUT("/studios/:id", handlers.UpdateStudio)
+		admin.PUT("/studios/:id/layout", handlers.ReplaceStudioLayout)
+		admin.PUT("/studios/:id/layout/import", handlers.ImportStudioLayout)
+		admin.DELETE("/studios/:id", handlers.DeleteStudio)
+		admin.PATCH("/seats/:id", handlers.SetSeatMaintenance)
+
+		admin.POST("/movies", handlers.CreateMovie)
+		admin.PUT("/movies/:id", handlers.UpdateMovie)
+		admin.DELETE("/movies/:id", handlers.DeleteMovie)
+
+		admin.POST("/showtimes", handlers.CreateShowtime)
+		admin.PUT("/showtimes/:id", handlers.UpdateShowtime)
+		admin.DELETE("/showtimes/:id", handlers.DeleteShowtime)
 	}
 
 	r.GET("/health", func(c *gin.Context) {
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceTokenHeader carries the token other services present to cinema
+// service.
+const ServiceTokenHeader = "X-Service-Token"
+
+// RequireServiceToken lets the request through only when it carries the
+// SERVICE_TOKEN shared with the other services. Without a configured token
+// every request is refused.
+func RequireServiceToken() gin.HandlerFunc {
+	token := os.Getenv("SERVICE_TOKEN")
+
+	return func(c *gin.Context) {
+		given := c.GetHeader(ServiceTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid service token"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -8,25 +8,126 @@ import (
 type Studio struct {
 	ID         uint           `json:"id" gorm:"primaryKey"`
 	Name       string         `json:"name" gorm:"not null"`
-	TotalSeats int            `json:"total_seats" gorm:"default:20"`
+	TotalSeats int            `json:"total_seats"`
+	Rows       int            `json:"rows"`
+	Columns    int            `json:"columns"`
+	GapRule    string         `json:"gap_rule" gorm:"not null;default:off"`
 	Seats      []Seat         `json:"seats,omitempty" gorm:"foreignKey:StudioID"`
+	Aisles     []Aisle        `json:"aisles,omitempty" gorm:"foreignKey:StudioID"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type Seat struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	StudioID    uint           `json:"studio_id" gorm:"not null"`
-	SeatNumber  string         `json:"seat_number" gorm:"not null"`
-	IsAvailable bool           `json:"is_available" gorm:"default:true"`
-	Studio      Studio         `json:"studio,omitempty" gorm:"foreignKey:StudioID"`
-	StudioName  string         `json:"studio_name,omitempty" gorm:"-"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	StudioID        uint           `json:"studio_id" gorm:"not null"`
+	SeatNumber      string         `json:"seat_number" gorm:"not null"`
+	RowLabel        string         `json:"row"`
+	RowIndex        int            `json:"row_index"`
+	ColumnIndex     int            `json:"column_index"`
+	SeatType        string         `json:"seat_type" gorm:"default:standard"`
+	PairSeatID      *uint          `json:"pair_seat_id,omitempty"`
+	OutOfService    bool           `json:"out_of_service"`
+	MaintenanceNote string         `json:"maintenance_note,omitempty"`
+	IsAvailable     bool           `json:"is_available" gorm:"-"`
+	Status          string         `json:"status,omitempty" gorm:"-"`
+	Studio          *Studio        `json:"studio,omitempty" gorm:"foreignKey:StudioID"`
+	StudioName      string         `json:"studio_name,omitempty" gorm:"-"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// Seat states tracked per showtime in SeatInventory.
+const (
+	SeatStatusAvailable = "available"
+	SeatStatusHeld      = "held"
+	SeatStatusSold      = "sold"
+
+	// SeatStatusOutOfService is reported for seats under maintenance; it
+	// is never stored in SeatInventory.
+	SeatStatusOutOfService = "out_of_service"
+
+	// SeatStatusNotFound is reported for requested seats that have no
+	// inventory for the showtime, such as seats of another studio.
+	SeatStatusNotFound = "not_found"
+)
+
+// SeatInventory holds the state of one physical seat for one showtime, so
+// selling A5 for the 19:00 screening leaves A5 free for every other one.
+type SeatInventory struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	ShowtimeID uint       `json:"showtime_id" gorm:"not null;uniqueIndex:idx_showtime_seat"`
+	SeatID     uint       `json:"seat_id" gorm:"not null;uniqueIndex:idx_showtime_seat;index"`
+	Status     string     `json:"status" gorm:"not null;default:available"`
+	HoldToken  string     `json:"-" gorm:"index"`
+	HeldUntil  *time.Time `json:"held_until,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// SeatReservationRequest names seats of one showtime. StudioID is optional;
+// when set it must be the studio the showtime plays in.
 type SeatReservationRequest struct {
-	SeatIDs []uint `json:"seatIds"`
-}
\ No newline at end of file
+	ShowtimeID uint   `json:"showtimeId"`
+	StudioID   uint   `json:"studioId"`
+	SeatIDs    []uint `json:"seatIds"`
+}
+
+type SeatHoldRequest struct {
+	ShowtimeID uint   `json:"showtimeId"`
+	StudioID   uint   `json:"studioId"`
+	SeatIDs    []uint `json:"seatIds"`
+	TTLSeconds int    `json:"ttlSeconds"`
+}
+
+type HoldTokenRequest struct {
+	HoldToken string `json:"holdToken"`
+}
+
+// SeatConflict names a requested seat that could not be claimed and the
+// state it was found in.
+type SeatConflict struct {
+	SeatID     uint   `json:"seatId"`
+	SeatNumber string `json:"seatNumber,omitempty"`
+	Status     string `json:"status"`
+}
+
+// SeatHold describes seats temporarily set aside for one checkout.
+// GapWarnings lists the seats the hold strands in studios that warn about
+// single-seat gaps.
+type SeatHold struct {
+	HoldToken   string    `json:"holdToken"`
+	ShowtimeID  uint      `json:"showtimeId"`
+	SeatIDs     []uint    `json:"seatIds"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	GapWarnings []SeatGap `json:"gapWarnings,omitempty"`
+}
+
+// SeatEvent announces that seats of a showtime changed state. Status is one
+// of the seat states, available meaning the seats were released.
+type SeatEvent struct {
+	ShowtimeID uint      `json:"showtimeId"`
+	SeatIDs    []uint    `json:"seatIds"`
+	Status     string    `json:"status"`
+	At         time.Time `json:"at"`
+}
+
+// SeatSelection answers a tentative seat selection made over the seat
+// selection channel: the seats now soft-locked for the client and those
+// someone else is already selecting.
+type SeatSelection struct {
+	SeatIDs   []uint    `json:"seatIds"`
+	Rejected  []uint    `json:"rejected,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// SeatSelectionEvent announces that a client started or stopped selecting
+// seats of a showtime.
+type SeatSelectionEvent struct {
+	ShowtimeID uint   `json:"showtimeId"`
+	SeatIDs    []uint `json:"seatIds"`
+	Selecting  bool   `json:"selecting"`
+	Owner      string `json:"-"`
+}
@@ -0,0 +1,107 @@
+package models
+
+// Seat types a studio layout can contain. Couch seats always come in
+// adjacent pairs linked through Seat.PairSeatID.
+const (
+	SeatTypeStandard   = "standard"
+	SeatTypePremium    = "premium"
+	SeatTypeWheelchair = "wheelchair"
+	SeatTypeCompanion  = "companion"
+	SeatTypeCouch      = "couch"
+)
+
+// Gap rules decide what happens when a selection would leave a single empty
+// seat between taken seats or against the end of a row.
+const (
+	GapRuleOff    = "off"
+	GapRuleWarn   = "warn"
+	GapRuleReject = "reject"
+)
+
+// Kinds of cell in a rendered studio layout.
+const (
+	CellSeat  = "seat"
+	CellAisle = "aisle"
+	CellGap   = "gap"
+)
+
+// Aisle is a walkway running through every row of a studio at one grid
+// column.
+type Aisle struct {
+	ID       uint `json:"id" gorm:"primaryKey"`
+	StudioID uint `json:"studio_id" gorm:"not null;index"`
+	Column   int  `json:"column" gorm:"column:column_index;not null"`
+}
+
+// StudioLayout is the seat map of a studio as a grid, row 1 being the row
+// closest to the screen and column 1 the leftmost column seen from the
+// audience.
+type StudioLayout struct {
+	StudioID     uint        `json:"studioId"`
+	Name         string      `json:"name"`
+	ShowtimeID   uint        `json:"showtimeId,omitempty"`
+	RowCount     int         `json:"rowCount"`
+	ColumnCount  int         `json:"columnCount"`
+	AisleColumns []int       `json:"aisleColumns"`
+	Rows         []LayoutRow `json:"rows"`
+}
+
+type LayoutRow struct {
+	Index int          `json:"index"`
+	Label string       `json:"label"`
+	Cells []LayoutCell `json:"cells"`
+}
+
+type LayoutCell struct {
+	Column int    `json:"column"`
+	Kind   string `json:"kind"`
+	Seat   *Seat  `json:"seat,omitempty"`
+}
+
+// StudioRequest creates or updates a studio. An empty GapRule keeps the
+// current rule, or off for a new studio.
+type StudioRequest struct {
+	Name    string               `json:"name"`
+	GapRule string               `json:"gapRule,omitempty"`
+	Layout  *StudioLayoutRequest `json:"layout,omitempty"`
+}
+
+// StudioLayoutRequest replaces the whole seat map of a studio. Seats keep
+// their ID when a seat with the same number already exists.
+type StudioLayoutRequest struct {
+	AisleColumns []int             `json:"aisleColumns"`
+	Seats        []SeatLayoutInput `json:"seats"`
+}
+
+// SeatLayoutInput places one seat. SeatNumber defaults to the row label
+// followed by the seat's position within its row, counted from the left.
+type SeatLayoutInput struct {
+	Row        string `json:"row"`
+	RowIndex   int    `json:"rowIndex"`
+	Column     int    `json:"column"`
+	SeatNumber string `json:"seatNumber"`
+	SeatType   string `json:"seatType"`
+}
+
+type SeatMaintenanceRequest struct {
+	OutOfService bool   `json:"outOfService"`
+	Note         string `json:"note"`
+}
+
+// SeatSuggestion is a block of adjacent available seats in one row offered
+// to a customer or cashier. Lower scores are better.
+type SeatSuggestion struct {
+	Row         string   `json:"row"`
+	SeatIDs     []uint   `json:"seatIds"`
+	SeatNumbers []string `json:"seatNumbers"`
+	OrphanSeats int      `json:"orphanSeats"`
+	Score       float64  `json:"score"`
+}
+
+// SeatGap is a single empty seat a selection would strand between taken
+// seats or against the end of its row.
+type SeatGap struct {
+	SeatID     uint   `json:"seatId"`
+	SeatNumber string `json:"seatNumber"`
+	Row        string `json:"row"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type Movie struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Title          string         `json:"title" gorm:"not null"`
+	Description    string         `json:"description"`
+	RuntimeMinutes int            `json:"runtime_minutes" gorm:"not null"`
+	Rating         string         `json:"rating"`
+	PosterURL      string         `json:"poster_url"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type Showtime struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	MovieID   uint           `json:"movie_id" gorm:"not null;index"`
+	StudioID  uint           `json:"studio_id" gorm:"not null;index"`
+	StartTime time.Time      `json:"start_time" gorm:"not null;index"`
+	EndTime   time.Time      `json:"end_time" gorm:"not null"`
+	Format    string         `json:"format" gorm:"default:2D"`
+	Movie     *Movie         `json:"movie,omitempty" gorm:"foreignKey:MovieID"`
+	Studio    *Studio        `json:"studio,omitempty" gorm:"foreignKey:StudioID"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type MovieRequest struct {
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	RuntimeMinutes int    `json:"runtimeMinutes"`
+	Rating         string `json:"rating"`
+	PosterURL      string `json:"posterUrl"`
+}
+
+type ShowtimeRequest struct {
+	MovieID   uint      `json:"movieId"`
+	StudioID  uint      `json:"studioId"`
+	StartTime time.Time `json:"startTime"`
+	Format    string    `json:"format"`
+}
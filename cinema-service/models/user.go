@@ -0,0 +1,9 @@
+package models
+
+// User is the authenticated caller as reported by auth-service.
+type User struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+}
@@ -1,12 +1,63 @@
 package services
 
 import (
-	"fmt"
+	"errors"
 
 	"cinema-service/database"
 	"cinema-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSeatsUnavailable       = errors.New("some seats are not available")
+	ErrShowtimeRequired       = errors.New("showtimeId is required")
+	ErrShowtimeStudioMismatch = errors.New("showtime does not belong to this studio")
+	ErrShowtimeHasSales       = errors.New("showtime already has sold seats")
+	ErrNoSeatsRequested       = errors.New("seatIds must contain at least one seat")
+	ErrDuplicateSeats         = errors.New("seatIds lists the same seat more than once")
+	ErrSeatsNotInStudio       = errors.New("some seats do not belong to the showtime's studio")
 )
 
+// SeatSelectionError rejects a seat list before any seat is claimed and
+// names the seats at fault.
+type SeatSelectionError struct {
+	Err     error
+	SeatIDs []uint
+}
+
+func (e *SeatSelectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SeatSelectionError) Unwrap() error {
+	return e.Err
+}
+
+// SeatConflictError lists the requested seats that could not be claimed
+// because they were missing, out of service, held or sold.
+type SeatConflictError struct {
+	Seats []models.SeatConflict
+}
+
+func (e *SeatConflictError) Error() string {
+	return ErrSeatsUnavailable.Error()
+}
+
+// SeatIDs returns the IDs of the conflicting seats.
+func (e *SeatConflictError) SeatIDs() []uint {
+	ids := make([]uint, len(e.Seats))
+	for i, seat := range e.Seats {
+		ids[i] = seat.SeatID
+	}
+	return ids
+}
+
+func (e *SeatConflictError) Unwrap() error {
+	return ErrSeatsUnavailable
+}
+
 func GetAllStudios() ([]models.Studio, error) {
 	var studios []models.Studio
 	result := database.DB.Find(&studios)
@@ -16,53 +67,286 @@ func GetAllStudios() ([]models.Studio, error) {
 	return studios, nil
 }
 
-func GetStudioSeats(studioID string) ([]models.Seat, error) {
+// GetStudioSeats returns the physical seats of a studio. When showtimeID is
+// non-zero each seat carries its state for that screening; otherwise every
+// seat in service is reported as available.
+func GetStudioSeats(studioID, showtimeID uint) ([]models.Seat, error) {
 	var seats []models.Seat
-	result := database.DB.Preload("Studio").Where("studio_id = ?", studioID).Order("seat_number").Find(&seats)
+	result := database.DB.Preload("Studio").Where("studio_id = ?", studioID).Order("row_index, column_index, seat_number").Find(&seats)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
-	// Set studio name for response
+	statuses := map[uint]string{}
+	if showtimeID != 0 {
+		var showtime models.Showtime
+		if err := database.DB.First(&showtime, showtimeID).Error; err != nil {
+			return nil, ErrShowtimeNotFound
+		}
+		if showtime.StudioID != studioID {
+			return nil, ErrShowtimeStudioMismatch
+		}
+
+		var inventory []models.SeatInventory
+		if err := database.DB.Where("showtime_id = ?", showtimeID).Find(&inventory).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range inventory {
+			statuses[item.SeatID] = item.Status
+		}
+	}
+
+	// Set studio name and availability for response
 	for i := range seats {
-		seats[i].StudioName = seats[i].Studio.Name
+		if seats[i].Studio != nil {
+			seats[i].StudioName = seats[i].Studio.Name
+		}
+		seats[i].IsAvailable = true
+		if seats[i].OutOfService {
+			seats[i].IsAvailable = false
+			seats[i].Status = models.SeatStatusOutOfService
+		} else if showtimeID != 0 {
+			status, ok := statuses[seats[i].ID]
+			if !ok {
+				status = models.SeatStatusAvailable
+			}
+			seats[i].Status = status
+			seats[i].IsAvailable = status == models.SeatStatusAvailable
+		}
 	}
 
 	return seats, nil
 }
 
-func ReserveSeats(seatIDs []uint) error {
-	tx := database.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+// sellableSeats leaves out inventory of seats taken out of service.
+func sellableSeats(db *gorm.DB) *gorm.DB {
+	return db.Where("seat_id NOT IN (SELECT id FROM seats WHERE out_of_service = ?)", true)
+}
+
+// ensureInventory creates the missing inventory rows of a showtime, one per
+// seat of its studio. It is safe to call repeatedly.
+func ensureInventory(tx *gorm.DB, showtimeID, studioID uint) error {
+	return tx.Exec(`INSERT INTO seat_inventories (showtime_id, seat_id, status, created_at, updated_at)
+		SELECT ?, id, ?, NOW(), NOW() FROM seats WHERE studio_id = ? AND deleted_at IS NULL
+		ON CONFLICT (showtime_id, seat_id) DO NOTHING`,
+		showtimeID, models.SeatStatusAvailable, studioID).Error
+}
+
+// claimSeats moves the requested seats of a showtime from available to the
+// state described by updates. The inventory rows are locked in seat order
+// before they are checked, so concurrent claims on overlapping seats queue
+// behind each other instead of both passing the availability check, and the
+// update itself only touches rows that are still available. In studios with
+// a gap rule the rest of the selected seats' rows is locked as well, since
+// the gap check reads it. Seats stranded by the claim are returned when the
+// studio's gap rule warns about them.
+func claimSeats(tx *gorm.DB, showtimeID, studioID uint, seatIDs []uint, updates map[string]interface{}) ([]models.SeatGap, error) {
+	showtimeStudioID, err := checkSeatOwnership(tx, showtimeID, studioID, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	lockIDs, err := seatsToLock(tx, showtimeStudioID, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []models.SeatInventory
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("showtime_id = ? AND seat_id IN ?", showtimeID, lockIDs).
+		Order("seat_id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var outOfService []uint
+	err = tx.Model(&models.Seat{}).
+		Where("id IN ? AND out_of_service = ?", seatIDs, true).
+		Pluck("id", &outOfService).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if conflicts := seatConflicts(seatIDs, rows, outOfService); len(conflicts) > 0 {
+		if err := nameConflictingSeats(tx, conflicts); err != nil {
+			return nil, err
 		}
-	}()
+		return nil, &SeatConflictError{Seats: conflicts}
+	}
 
-	// Check if seats are available
-	var availableSeats []models.Seat
-	result := tx.Where("id IN ? AND is_available = ?", seatIDs, true).Find(&availableSeats)
+	gaps, err := checkSeatGaps(tx, showtimeID, showtimeStudioID, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := tx.Model(&models.SeatInventory{}).
+		Where("showtime_id = ? AND seat_id IN ? AND status = ?", showtimeID, seatIDs, models.SeatStatusAvailable).
+		Updates(updates)
 	if result.Error != nil {
-		tx.Rollback()
-		return result.Error
+		return nil, result.Error
+	}
+	if result.RowsAffected != int64(len(seatIDs)) {
+		return nil, ErrSeatsUnavailable
 	}
+	return gaps, nil
+}
 
-	if len(availableSeats) != len(seatIDs) {
-		tx.Rollback()
-		return fmt.Errorf("some seats are not available")
+// seatConflicts returns, in request order, the requested seats that have no
+// inventory row, are not available or are out of service.
+func seatConflicts(requested []uint, rows []models.SeatInventory, outOfService []uint) []models.SeatConflict {
+	statuses := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		statuses[row.SeatID] = row.Status
+	}
+	for _, id := range outOfService {
+		statuses[id] = models.SeatStatusOutOfService
 	}
 
-	// Reserve seats
-	result = tx.Model(&models.Seat{}).Where("id IN ?", seatIDs).Update("is_available", false)
-	if result.Error != nil {
-		tx.Rollback()
-		return result.Error
+	var conflicts []models.SeatConflict
+	for _, id := range requested {
+		status, ok := statuses[id]
+		if !ok {
+			status = models.SeatStatusNotFound
+		}
+		if status != models.SeatStatusAvailable {
+			conflicts = append(conflicts, models.SeatConflict{SeatID: id, Status: status})
+		}
+	}
+	return conflicts
+}
+
+// nameConflictingSeats fills in the seat numbers of conflicting seats so
+// clients can show them to customers.
+func nameConflictingSeats(tx *gorm.DB, conflicts []models.SeatConflict) error {
+	ids := make([]uint, len(conflicts))
+	for i, conflict := range conflicts {
+		ids[i] = conflict.SeatID
 	}
 
-	return tx.Commit().Error
+	var seats []models.Seat
+	if err := tx.Select("id", "seat_number").Where("id IN ?", ids).Find(&seats).Error; err != nil {
+		return err
+	}
+	numbers := make(map[uint]string, len(seats))
+	for _, seat := range seats {
+		numbers[seat.ID] = seat.SeatNumber
+	}
+	for i := range conflicts {
+		conflicts[i].SeatNumber = numbers[conflicts[i].SeatID]
+	}
+	return nil
+}
+
+// checkSeatRequest rejects seat requests without a showtime, without seats
+// or naming a seat twice. It needs no database, so callers run it before
+// opening a transaction.
+func checkSeatRequest(showtimeID uint, seatIDs []uint) error {
+	if showtimeID == 0 {
+		return ErrShowtimeRequired
+	}
+	if len(seatIDs) == 0 {
+		return ErrNoSeatsRequested
+	}
+	if duplicates := duplicateSeatIDs(seatIDs); len(duplicates) > 0 {
+		return &SeatSelectionError{Err: ErrDuplicateSeats, SeatIDs: duplicates}
+	}
+	return nil
 }
 
-func ReleaseSeats(seatIDs []uint) error {
-	result := database.DB.Model(&models.Seat{}).Where("id IN ?", seatIDs).Update("is_available", true)
-	return result.Error
+// duplicateSeatIDs returns each seat ID that appears more than once, in the
+// order of its second occurrence.
+func duplicateSeatIDs(seatIDs []uint) []uint {
+	seen := make(map[uint]int, len(seatIDs))
+	var duplicates []uint
+	for _, id := range seatIDs {
+		seen[id]++
+		if seen[id] == 2 {
+			duplicates = append(duplicates, id)
+		}
+	}
+	return duplicates
+}
+
+// checkSeatOwnership makes sure the showtime exists, plays in studioID when
+// one is given, and that every seat belongs to the showtime's studio. It
+// returns the showtime's studio.
+func checkSeatOwnership(tx *gorm.DB, showtimeID, studioID uint, seatIDs []uint) (uint, error) {
+	var showtime models.Showtime
+	if err := tx.Select("id", "studio_id").First(&showtime, showtimeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrShowtimeNotFound
+		}
+		return 0, err
+	}
+	if studioID != 0 && showtime.StudioID != studioID {
+		return 0, ErrShowtimeStudioMismatch
+	}
+
+	var seats []models.Seat
+	if err := tx.Select("id", "studio_id").Where("id IN ?", seatIDs).Find(&seats).Error; err != nil {
+		return 0, err
+	}
+	if foreign := seatsOutsideStudio(seatIDs, seats, showtime.StudioID); len(foreign) > 0 {
+		return 0, &SeatSelectionError{Err: ErrSeatsNotInStudio, SeatIDs: foreign}
+	}
+	return showtime.StudioID, nil
+}
+
+// seatsOutsideStudio returns, in request order, the requested seats that do
+// not exist or belong to another studio.
+func seatsOutsideStudio(requested []uint, seats []models.Seat, studioID uint) []uint {
+	inStudio := make(map[uint]bool, len(seats))
+	for _, seat := range seats {
+		inStudio[seat.ID] = seat.StudioID == studioID
+	}
+
+	var foreign []uint
+	for _, id := range requested {
+		if !inStudio[id] {
+			foreign = append(foreign, id)
+		}
+	}
+	return foreign
+}
+
+// ReserveSeats sells seats for a showtime. Either every seat is sold or none
+// is; a *SeatConflictError names the seats that were not available and why.
+// The returned gaps are seats the sale strands in a studio whose gap rule
+// warns.
+func ReserveSeats(req models.SeatReservationRequest) ([]models.SeatGap, error) {
+	if err := checkSeatRequest(req.ShowtimeID, req.SeatIDs); err != nil {
+		return nil, err
+	}
+
+	var gaps []models.SeatGap
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		gaps, err = claimSeats(tx, req.ShowtimeID, req.StudioID, req.SeatIDs, map[string]interface{}{
+			"status": models.SeatStatusSold,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishSeatChange(req.ShowtimeID, req.SeatIDs, models.SeatStatusSold)
+	return gaps, nil
+}
+
+func ReleaseSeats(showtimeID uint, seatIDs []uint) error {
+	var released []models.SeatInventory
+	result := database.DB.Model(&released).
+		Clauses(clause.Returning{}).
+		Where("showtime_id = ? AND seat_id IN ? AND status <> ?", showtimeID, seatIDs, models.SeatStatusAvailable).
+		Updates(map[string]interface{}{
+			"status":     models.SeatStatusAvailable,
+			"hold_token": "",
+			"held_until": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	publishInventoryChange(released, models.SeatStatusAvailable)
+	return nil
 }
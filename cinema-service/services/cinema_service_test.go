@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeatConflicts(t *testing.T) {
+	rows := []models.SeatInventory{
+		{SeatID: 1, Status: models.SeatStatusAvailable},
+		{SeatID: 2, Status: models.SeatStatusHeld},
+		{SeatID: 3, Status: models.SeatStatusSold},
+		{SeatID: 4, Status: models.SeatStatusAvailable},
+		{SeatID: 5, Status: models.SeatStatusAvailable},
+	}
+
+	tests := []struct {
+		name         string
+		requested    []uint
+		outOfService []uint
+		expected     []models.SeatConflict
+	}{
+		{
+			name:      "All available",
+			requested: []uint{1, 4, 5},
+			expected:  nil,
+		},
+		{
+			name:      "Held and sold seats conflict",
+			requested: []uint{3, 1, 2},
+			expected: []models.SeatConflict{
+				{SeatID: 3, Status: models.SeatStatusSold},
+				{SeatID: 2, Status: models.SeatStatusHeld},
+			},
+		},
+		{
+			name:      "Seat without inventory conflicts",
+			requested: []uint{1, 99},
+			expected:  []models.SeatConflict{{SeatID: 99, Status: models.SeatStatusNotFound}},
+		},
+		{
+			name:         "Out of service seat conflicts",
+			requested:    []uint{4, 5},
+			outOfService: []uint{5},
+			expected:     []models.SeatConflict{{SeatID: 5, Status: models.SeatStatusOutOfService}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, seatConflicts(tt.requested, rows, tt.outOfService))
+		})
+	}
+}
+
+func TestCheckSeatRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		showtimeID    uint
+		seatIDs       []uint
+		expectedError error
+		expectedSeats []uint
+	}{
+		{
+			name:       "Valid request",
+			showtimeID: 1,
+			seatIDs:    []uint{1, 2, 3},
+		},
+		{
+			name:          "Missing showtime",
+			seatIDs:       []uint{1},
+			expectedError: ErrShowtimeRequired,
+		},
+		{
+			name:          "No seats",
+			showtimeID:    1,
+			seatIDs:       []uint{},
+			expectedError: ErrNoSeatsRequested,
+		},
+		{
+			name:          "Duplicate seats",
+			showtimeID:    1,
+			seatIDs:       []uint{3, 1, 3, 2, 1, 3},
+			expectedError: ErrDuplicateSeats,
+			expectedSeats: []uint{3, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSeatRequest(tt.showtimeID, tt.seatIDs)
+			if tt.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, tt.expectedError))
+			var selection *SeatSelectionError
+			if tt.expectedSeats != nil && assert.True(t, errors.As(err, &selection)) {
+				assert.Equal(t, tt.expectedSeats, selection.SeatIDs)
+			}
+		})
+	}
+}
+
+func TestSeatsOutsideStudio(t *testing.T) {
+	seats := []models.Seat{
+		{ID: 1, StudioID: 1},
+		{ID: 2, StudioID: 1},
+		{ID: 7, StudioID: 4},
+	}
+
+	assert.Nil(t, seatsOutsideStudio([]uint{1, 2}, seats, 1))
+	assert.Equal(t, []uint{7, 99}, seatsOutsideStudio([]uint{7, 1, 99}, seats, 1))
+}
+
+func TestSeatConflictErrorUnwrap(t *testing.T) {
+	conflict := &SeatConflictError{Seats: []models.SeatConflict{
+		{SeatID: 7, SeatNumber: "A7", Status: models.SeatStatusSold},
+		{SeatID: 9, SeatNumber: "A9", Status: models.SeatStatusHeld},
+	}}
+	var err error = conflict
+
+	assert.True(t, errors.Is(err, ErrSeatsUnavailable))
+	assert.Equal(t, ErrSeatsUnavailable.Error(), err.Error())
+	assert.Equal(t, []uint{7, 9}, conflict.SeatIDs())
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+
+	"cinema-service/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidGapRule = errors.New("gapRule must be off, warn or reject")
+	ErrSeatGap        = errors.New("selection would leave a single empty seat")
+)
+
+var validGapRules = map[string]bool{
+	models.GapRuleOff:    true,
+	models.GapRuleWarn:   true,
+	models.GapRuleReject: true,
+}
+
+// SeatGapError rejects a selection in a studio whose gap rule is reject and
+// names the seats it would strand.
+type SeatGapError struct {
+	Seats []models.SeatGap
+}
+
+func (e *SeatGapError) Error() string {
+	return ErrSeatGap.Error()
+}
+
+func (e *SeatGapError) Unwrap() error {
+	return ErrSeatGap
+}
+
+// seatsToLock returns the seats whose inventory rows a claim must lock
+// before it is checked: the selection itself and, in studios with a gap
+// rule, every seat in the rows it touches. Claims on either side of a free
+// seat then queue behind each other, so both cannot pass the gap check
+// before either is saved and strand the seat between them.
+func seatsToLock(tx *gorm.DB, studioID uint, seatIDs []uint) ([]uint, error) {
+	var studio models.Studio
+	if err := tx.Select("id", "gap_rule").First(&studio, studioID).Error; err != nil {
+		return nil, err
+	}
+	if studio.GapRule != models.GapRuleWarn && studio.GapRule != models.GapRuleReject {
+		return seatIDs, nil
+	}
+
+	var ids []uint
+	err := tx.Model(&models.Seat{}).
+		Where("studio_id = ? AND row_index IN (?)", studioID,
+			tx.Model(&models.Seat{}).Select("row_index").Where("id IN ?", seatIDs)).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// checkSeatGaps applies the studio's gap rule to a selection of available
+// seats. It returns the stranded seats when the rule only warns and a
+// *SeatGapError when it rejects. The caller must hold the locks from
+// seatsToLock so the neighbouring seats cannot change under the check.
+func checkSeatGaps(tx *gorm.DB, showtimeID, studioID uint, seatIDs []uint) ([]models.SeatGap, error) {
+	var studio models.Studio
+	if err := tx.Select("id", "gap_rule").First(&studio, studioID).Error; err != nil {
+		return nil, err
+	}
+	if studio.GapRule != models.GapRuleWarn && studio.GapRule != models.GapRuleReject {
+		return nil, nil
+	}
+
+	var seats []models.Seat
+	err := tx.Select("id", "seat_number", "row_label", "row_index", "column_index", "out_of_service").
+		Where("studio_id = ?", studioID).
+		Order("row_index, column_index").
+		Find(&seats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var takenIDs []uint
+	err = tx.Model(&models.SeatInventory{}).
+		Where("showtime_id = ? AND status <> ?", showtimeID, models.SeatStatusAvailable).
+		Pluck("seat_id", &takenIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[uint]bool, len(takenIDs))
+	for _, id := range takenIDs {
+		taken[id] = true
+	}
+	selected := make(map[uint]bool, len(seatIDs))
+	for _, id := range seatIDs {
+		selected[id] = true
+	}
+
+	gaps := strandedSeats(seats, taken, selected)
+	if len(gaps) > 0 && studio.GapRule == models.GapRuleReject {
+		return nil, &SeatGapError{Seats: gaps}
+	}
+	return gaps, nil
+}
+
+// strandedSeats finds the free seats the selection would leave on their
+// own. A seat is stranded when neither side has a free neighbour and at
+// least one side is a selected seat; aisles, gaps and the ends of a row
+// count as walls, and out-of-service seats count as taken. Gaps that exist
+// without the selection are not its fault and are not reported.
+func strandedSeats(seats []models.Seat, taken, selected map[uint]bool) []models.SeatGap {
+	grid := make(map[[2]int]*models.Seat, len(seats))
+	for i := range seats {
+		grid[[2]int{seats[i].RowIndex, seats[i].ColumnIndex}] = &seats[i]
+	}
+
+	free := func(seat *models.Seat) bool {
+		return seat != nil && !seat.OutOfService && !taken[seat.ID] && !selected[seat.ID]
+	}
+	isSelected := func(seat *models.Seat) bool {
+		return seat != nil && selected[seat.ID]
+	}
+
+	var gaps []models.SeatGap
+	for i := range seats {
+		seat := &seats[i]
+		if !free(seat) {
+			continue
+		}
+		left := grid[[2]int{seat.RowIndex, seat.ColumnIndex - 1}]
+		right := grid[[2]int{seat.RowIndex, seat.ColumnIndex + 1}]
+		if free(left) || free(right) {
+			continue
+		}
+		if isSelected(left) || isSelected(right) {
+			gaps = append(gaps, models.SeatGap{SeatID: seat.ID, SeatNumber: seat.SeatNumber, Row: seat.RowLabel})
+		}
+	}
+	return gaps
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrandedSeats(t *testing.T) {
+	// Row A: seats in columns 1-4, an aisle at 5, seats in 6-7.
+	// Row B: seats in columns 1-3, the middle one out of service.
+	seats := []models.Seat{
+		{ID: 1, SeatNumber: "A1", RowLabel: "A", RowIndex: 1, ColumnIndex: 1},
+		{ID: 2, SeatNumber: "A2", RowLabel: "A", RowIndex: 1, ColumnIndex: 2},
+		{ID: 3, SeatNumber: "A3", RowLabel: "A", RowIndex: 1, ColumnIndex: 3},
+		{ID: 4, SeatNumber: "A4", RowLabel: "A", RowIndex: 1, ColumnIndex: 4},
+		{ID: 6, SeatNumber: "A6", RowLabel: "A", RowIndex: 1, ColumnIndex: 6},
+		{ID: 7, SeatNumber: "A7", RowLabel: "A", RowIndex: 1, ColumnIndex: 7},
+		{ID: 8, SeatNumber: "B1", RowLabel: "B", RowIndex: 2, ColumnIndex: 1},
+		{ID: 9, SeatNumber: "B2", RowLabel: "B", RowIndex: 2, ColumnIndex: 2, OutOfService: true},
+		{ID: 10, SeatNumber: "B3", RowLabel: "B", RowIndex: 2, ColumnIndex: 3},
+	}
+
+	tests := []struct {
+		name     string
+		taken    []uint
+		selected []uint
+		expected []uint
+	}{
+		{
+			name:     "Leaving two free seats together is fine",
+			selected: []uint{1, 2},
+		},
+		{
+			name:     "Gap between selection and taken seat",
+			taken:    []uint{3},
+			selected: []uint{1},
+			expected: []uint{2},
+		},
+		{
+			name:     "Gap against the end of the row",
+			selected: []uint{1, 2, 3},
+			expected: []uint{4},
+		},
+		{
+			name:     "Gap against an aisle",
+			selected: []uint{7},
+			expected: []uint{6},
+		},
+		{
+			name:     "Out of service seats count as taken",
+			selected: []uint{10},
+		},
+		{
+			name:     "Existing gaps are not the selection's fault",
+			taken:    []uint{6},
+			selected: []uint{1, 2},
+		},
+		{
+			name:     "Filling a gap is fine",
+			taken:    []uint{1, 3},
+			selected: []uint{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := map[uint]bool{}
+			for _, id := range tt.taken {
+				taken[id] = true
+			}
+			selected := map[uint]bool{}
+			for _, id := range tt.selected {
+				selected[id] = true
+			}
+
+			var stranded []uint
+			for _, gap := range strandedSeats(seats, taken, selected) {
+				stranded = append(stranded, gap.SeatID)
+			}
+			assert.Equal(t, tt.expected, stranded)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"cinema-service/database"
+	"cinema-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrHoldTokenRequired = errors.New("holdToken is required")
+	ErrHoldNotFound      = errors.New("hold not found or expired")
+)
+
+// maxHoldTTL caps the hold duration a client may ask for.
+const maxHoldTTL = 30 * time.Minute
+
+// holdTTL is how long seats stay held when the client does not ask for a
+// specific duration.
+func holdTTL() time.Duration {
+	if v := os.Getenv("SEAT_HOLD_TTL_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return 10 * time.Minute
+}
+
+// resolveHoldTTL turns the requested number of seconds into a hold
+// duration, falling back to the default and never exceeding maxHoldTTL.
+func resolveHoldTTL(ttlSeconds int) time.Duration {
+	ttl := holdTTL()
+	if ttlSeconds > 0 {
+		ttl = time.Duration(ttlSeconds) * time.Second
+	}
+	if ttl > maxHoldTTL {
+		ttl = maxHoldTTL
+	}
+	return ttl
+}
+
+func newHoldToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HoldSeats sets seats aside for a showtime until the hold is confirmed,
+// released or expires.
+func HoldSeats(req models.SeatHoldRequest) (*models.SeatHold, error) {
+	if err := checkSeatRequest(req.ShowtimeID, req.SeatIDs); err != nil {
+		return nil, err
+	}
+
+	token, err := newHoldToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(resolveHoldTTL(req.TTLSeconds))
+
+	var gaps []models.SeatGap
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		gaps, err = claimSeats(tx, req.ShowtimeID, req.StudioID, req.SeatIDs, map[string]interface{}{
+			"status":     models.SeatStatusHeld,
+			"hold_token": token,
+			"held_until": expiresAt,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishSeatChange(req.ShowtimeID, req.SeatIDs, models.SeatStatusHeld)
+
+	return &models.SeatHold{
+		HoldToken:   token,
+		ShowtimeID:  req.ShowtimeID,
+		SeatIDs:     req.SeatIDs,
+		ExpiresAt:   expiresAt,
+		GapWarnings: gaps,
+	}, nil
+}
+
+// ConfirmHold turns a live hold into a sale. Confirming a hold that was
+// already confirmed succeeds again, so callers can safely retry.
+func ConfirmHold(token string) error {
+	if token == "" {
+		return ErrHoldTokenRequired
+	}
+
+	var confirmed []models.SeatInventory
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&confirmed).
+			Clauses(clause.Returning{}).
+			Where("hold_token = ? AND status = ? AND held_until > ?", token, models.SeatStatusHeld, time.Now()).
+			Updates(map[string]interface{}{
+				"status":     models.SeatStatusSold,
+				"held_until": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+
+		var sold int64
+		err := tx.Model(&models.SeatInventory{}).
+			Where("hold_token = ? AND status = ?", token, models.SeatStatusSold).
+			Count(&sold).Error
+		if err != nil {
+			return err
+		}
+		if sold == 0 {
+			return ErrHoldNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	publishInventoryChange(confirmed, models.SeatStatusSold)
+	return nil
+}
+
+// ReleaseHold gives held seats back before their hold expires.
+func ReleaseHold(token string) error {
+	if token == "" {
+		return ErrHoldTokenRequired
+	}
+
+	var released []models.SeatInventory
+	err := database.DB.Model(&released).
+		Clauses(clause.Returning{}).
+		Where("hold_token = ? AND status = ?", token, models.SeatStatusHeld).
+		Updates(map[string]interface{}{
+			"status":     models.SeatStatusAvailable,
+			"hold_token": "",
+			"held_until": nil,
+		}).Error
+	if err != nil {
+		return err
+	}
+	publishInventoryChange(released, models.SeatStatusAvailable)
+	return nil
+}
+
+// ReleaseExpiredHolds frees every hold whose TTL has passed and returns how
+// many seats went back on sale.
+func ReleaseExpiredHolds() (int64, error) {
+	var released []models.SeatInventory
+	result := database.DB.Model(&released).
+		Clauses(clause.Returning{}).
+		Where("status = ? AND held_until <= ?", models.SeatStatusHeld, time.Now()).
+		Updates(map[string]interface{}{
+			"status":     models.SeatStatusAvailable,
+			"hold_token": "",
+			"held_until": nil,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	publishInventoryChange(released, models.SeatStatusAvailable)
+	return result.RowsAffected, nil
+}
+
+// StartHoldSweeper releases expired holds every interval until the process
+// exits.
+func StartHoldSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			released, err := ReleaseExpiredHolds()
+			if err != nil {
+				log.Println("Failed to release expired seat holds:", err)
+				continue
+			}
+			if released > 0 {
+				log.Printf("Released %d seats from expired holds", released)
+			}
+		}
+	}()
+}
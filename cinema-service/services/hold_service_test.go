@@ -0,0 +1,41 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveHoldTTL(t *testing.T) {
+	defer os.Unsetenv("SEAT_HOLD_TTL_MINUTES")
+	os.Unsetenv("SEAT_HOLD_TTL_MINUTES")
+
+	assert.Equal(t, 10*time.Minute, resolveHoldTTL(0))
+	assert.Equal(t, 90*time.Second, resolveHoldTTL(90))
+	assert.Equal(t, maxHoldTTL, resolveHoldTTL(int((2 * time.Hour).Seconds())))
+
+	os.Setenv("SEAT_HOLD_TTL_MINUTES", "5")
+	assert.Equal(t, 5*time.Minute, resolveHoldTTL(0))
+}
+
+func TestNewHoldToken(t *testing.T) {
+	first, err := newHoldToken()
+	assert.NoError(t, err)
+	assert.Len(t, first, 32)
+
+	second, err := newHoldToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHoldValidation(t *testing.T) {
+	_, err := HoldSeats(models.SeatHoldRequest{SeatIDs: []uint{1}})
+	assert.ErrorIs(t, err, ErrShowtimeRequired)
+
+	assert.ErrorIs(t, ConfirmHold(""), ErrHoldTokenRequired)
+	assert.ErrorIs(t, ReleaseHold(""), ErrHoldTokenRequired)
+}
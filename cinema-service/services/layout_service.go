@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sort"
+
+	"cinema-service/database"
+	"cinema-service/models"
+)
+
+// GetStudioLayout returns the seat map of a studio, with per-seat state for
+// showtimeID when it is non-zero.
+func GetStudioLayout(studioID, showtimeID uint) (*models.StudioLayout, error) {
+	var studio models.Studio
+	if err := database.DB.Preload("Aisles").First(&studio, studioID).Error; err != nil {
+		return nil, ErrStudioNotFound
+	}
+
+	seats, err := GetStudioSeats(studioID, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range seats {
+		// The studio is described once at the top of the layout.
+		seats[i].Studio = nil
+	}
+
+	layout := buildLayout(studio, seats)
+	layout.ShowtimeID = showtimeID
+	return &layout, nil
+}
+
+// buildLayout arranges seats into a grid of rows and columns. Grid cells
+// without a seat are reported as aisles when their column is one of the
+// studio's aisles and as gaps otherwise.
+func buildLayout(studio models.Studio, seats []models.Seat) models.StudioLayout {
+	rowCount, columnCount := studio.Rows, studio.Columns
+	aisles := map[int]bool{}
+	aisleColumns := []int{}
+	for _, aisle := range studio.Aisles {
+		if !aisles[aisle.Column] {
+			aisles[aisle.Column] = true
+			aisleColumns = append(aisleColumns, aisle.Column)
+		}
+		if aisle.Column > columnCount {
+			columnCount = aisle.Column
+		}
+	}
+	sort.Ints(aisleColumns)
+
+	grid := map[[2]int]*models.Seat{}
+	labels := map[int]string{}
+	for i := range seats {
+		seat := &seats[i]
+		grid[[2]int{seat.RowIndex, seat.ColumnIndex}] = seat
+		if labels[seat.RowIndex] == "" {
+			labels[seat.RowIndex] = seat.RowLabel
+		}
+		if seat.RowIndex > rowCount {
+			rowCount = seat.RowIndex
+		}
+		if seat.ColumnIndex > columnCount {
+			columnCount = seat.ColumnIndex
+		}
+	}
+
+	rows := make([]models.LayoutRow, 0, rowCount)
+	for r := 1; r <= rowCount; r++ {
+		row := models.LayoutRow{Index: r, Label: labels[r], Cells: make([]models.LayoutCell, 0, columnCount)}
+		for col := 1; col <= columnCount; col++ {
+			cell := models.LayoutCell{Column: col, Kind: models.CellGap}
+			if seat, ok := grid[[2]int{r, col}]; ok {
+				cell.Kind = models.CellSeat
+				cell.Seat = seat
+			} else if aisles[col] {
+				cell.Kind = models.CellAisle
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	return models.StudioLayout{
+		StudioID:     studio.ID,
+		Name:         studio.Name,
+		RowCount:     rowCount,
+		ColumnCount:  columnCount,
+		AisleColumns: aisleColumns,
+		Rows:         rows,
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildLayout(t *testing.T) {
+	pairOf4, pairOf5 := uint(5), uint(4)
+	studio := models.Studio{
+		ID:      1,
+		Name:    "Studio 1",
+		Rows:    2,
+		Columns: 5,
+		Aisles:  []models.Aisle{{StudioID: 1, Column: 3}},
+	}
+	seats := []models.Seat{
+		{ID: 1, SeatNumber: "A1", RowLabel: "A", RowIndex: 1, ColumnIndex: 1, SeatType: models.SeatTypeWheelchair},
+		{ID: 2, SeatNumber: "A2", RowLabel: "A", RowIndex: 1, ColumnIndex: 2, SeatType: models.SeatTypeCompanion},
+		{ID: 3, SeatNumber: "A3", RowLabel: "A", RowIndex: 1, ColumnIndex: 5, SeatType: models.SeatTypeStandard},
+		{ID: 4, SeatNumber: "B1", RowLabel: "B", RowIndex: 2, ColumnIndex: 1, SeatType: models.SeatTypeCouch, PairSeatID: &pairOf4},
+		{ID: 5, SeatNumber: "B2", RowLabel: "B", RowIndex: 2, ColumnIndex: 2, SeatType: models.SeatTypeCouch, PairSeatID: &pairOf5},
+	}
+
+	layout := buildLayout(studio, seats)
+
+	assert.Equal(t, 2, layout.RowCount)
+	assert.Equal(t, 5, layout.ColumnCount)
+	assert.Equal(t, []int{3}, layout.AisleColumns)
+	assert.Len(t, layout.Rows, 2)
+
+	rowA := layout.Rows[0]
+	assert.Equal(t, "A", rowA.Label)
+	assert.Len(t, rowA.Cells, 5)
+	assert.Equal(t, models.CellSeat, rowA.Cells[0].Kind)
+	assert.Equal(t, models.SeatTypeWheelchair, rowA.Cells[0].Seat.SeatType)
+	assert.Equal(t, models.CellAisle, rowA.Cells[2].Kind)
+	assert.Nil(t, rowA.Cells[2].Seat)
+	assert.Equal(t, models.CellGap, rowA.Cells[3].Kind)
+	assert.Equal(t, "A3", rowA.Cells[4].Seat.SeatNumber)
+
+	rowB := layout.Rows[1]
+	assert.Equal(t, "B", rowB.Label)
+	assert.Equal(t, models.CellGap, rowB.Cells[4].Kind)
+}
+
+func TestBuildLayoutGrowsToFitSeats(t *testing.T) {
+	studio := models.Studio{ID: 1, Name: "Legacy"}
+	seats := []models.Seat{
+		{ID: 1, SeatNumber: "A1", RowLabel: "A", RowIndex: 1, ColumnIndex: 1},
+		{ID: 2, SeatNumber: "C4", RowLabel: "C", RowIndex: 3, ColumnIndex: 4},
+	}
+
+	layout := buildLayout(studio, seats)
+
+	assert.Equal(t, 3, layout.RowCount)
+	assert.Equal(t, 4, layout.ColumnCount)
+	assert.Equal(t, "", layout.Rows[1].Label)
+	for _, cell := range layout.Rows[1].Cells {
+		assert.Equal(t, models.CellGap, cell.Kind)
+	}
+}
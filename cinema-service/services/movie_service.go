@@ -0,0 +1,315 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cinema-service/database"
+	"cinema-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidMovie      = errors.New("title and a positive runtime are required")
+	ErrInvalidShowtime   = errors.New("movie, studio and start time are required")
+	ErrInvalidFormat     = errors.New("format must be one of 2D, 3D or IMAX")
+	ErrMovieHasShowtimes = errors.New("movie still has upcoming showtimes")
+	ErrStudioNotFound    = errors.New("studio not found")
+	ErrMovieNotFound     = errors.New("movie not found")
+	ErrShowtimeNotFound  = errors.New("showtime not found")
+	ErrInvalidDate       = errors.New("date must be in YYYY-MM-DD format")
+)
+
+// ShowtimeConflictError is returned when a showtime would overlap another
+// screening in the same studio once the cleaning buffer is applied.
+type ShowtimeConflictError struct {
+	Conflict models.Showtime
+}
+
+func (e *ShowtimeConflictError) Error() string {
+	return fmt.Sprintf("showtime overlaps showtime %d (%s - %s) in the same studio",
+		e.Conflict.ID, e.Conflict.StartTime.Format(time.RFC3339), e.Conflict.EndTime.Format(time.RFC3339))
+}
+
+var validFormats = map[string]bool{"2D": true, "3D": true, "IMAX": true}
+
+// cleaningBuffer is the time a studio needs between two screenings.
+func cleaningBuffer() time.Duration {
+	if v := os.Getenv("CLEANING_BUFFER_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes >= 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return 15 * time.Minute
+}
+
+// showtimesOverlap reports whether two screenings collide once the cleaning
+// buffer is added after each of them.
+func showtimesOverlap(aStart, aEnd, bStart, bEnd time.Time, buffer time.Duration) bool {
+	return aStart.Before(bEnd.Add(buffer)) && bStart.Before(aEnd.Add(buffer))
+}
+
+func GetAllMovies() ([]models.Movie, error) {
+	var movies []models.Movie
+	result := database.DB.Order("title").Find(&movies)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return movies, nil
+}
+
+func GetMovie(id uint) (*models.Movie, error) {
+	var movie models.Movie
+	if err := database.DB.First(&movie, id).Error; err != nil {
+		return nil, ErrMovieNotFound
+	}
+	return &movie, nil
+}
+
+func CreateMovie(req models.MovieRequest) (*models.Movie, error) {
+	if strings.TrimSpace(req.Title) == "" || req.RuntimeMinutes <= 0 {
+		return nil, ErrInvalidMovie
+	}
+
+	movie := models.Movie{
+		Title:          strings.TrimSpace(req.Title),
+		Description:    req.Description,
+		RuntimeMinutes: req.RuntimeMinutes,
+		Rating:         req.Rating,
+		PosterURL:      req.PosterURL,
+	}
+	if err := database.DB.Create(&movie).Error; err != nil {
+		return nil, err
+	}
+	return &movie, nil
+}
+
+// UpdateMovie changes the catalog entry only; showtimes that were already
+// scheduled keep the end time computed from the previous runtime.
+func UpdateMovie(id uint, req models.MovieRequest) (*models.Movie, error) {
+	if strings.TrimSpace(req.Title) == "" || req.RuntimeMinutes <= 0 {
+		return nil, ErrInvalidMovie
+	}
+
+	movie, err := GetMovie(id)
+	if err != nil {
+		return nil, err
+	}
+
+	movie.Title = strings.TrimSpace(req.Title)
+	movie.Description = req.Description
+	movie.RuntimeMinutes = req.RuntimeMinutes
+	movie.Rating = req.Rating
+	movie.PosterURL = req.PosterURL
+	if err := database.DB.Save(movie).Error; err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+func DeleteMovie(id uint) error {
+	if _, err := GetMovie(id); err != nil {
+		return err
+	}
+
+	var upcoming int64
+	err := database.DB.Model(&models.Showtime{}).
+		Where("movie_id = ? AND end_time > ?", id, time.Now()).
+		Count(&upcoming).Error
+	if err != nil {
+		return err
+	}
+	if upcoming > 0 {
+		return ErrMovieHasShowtimes
+	}
+
+	return database.DB.Delete(&models.Movie{}, id).Error
+}
+
+// GetShowtimes lists screenings, optionally restricted to a calendar day
+// (YYYY-MM-DD in the server's local time zone), a movie and a studio.
+func GetShowtimes(date string, movieID, studioID uint) ([]models.Showtime, error) {
+	query := database.DB.Preload("Movie").Preload("Studio").Order("start_time")
+
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			return nil, ErrInvalidDate
+		}
+		query = query.Where("start_time >= ? AND start_time < ?", day, day.AddDate(0, 0, 1))
+	}
+	if movieID != 0 {
+		query = query.Where("movie_id = ?", movieID)
+	}
+	if studioID != 0 {
+		query = query.Where("studio_id = ?", studioID)
+	}
+
+	var showtimes []models.Showtime
+	if err := query.Find(&showtimes).Error; err != nil {
+		return nil, err
+	}
+	return showtimes, nil
+}
+
+func GetShowtime(id uint) (*models.Showtime, error) {
+	var showtime models.Showtime
+	if err := database.DB.Preload("Movie").Preload("Studio").First(&showtime, id).Error; err != nil {
+		return nil, ErrShowtimeNotFound
+	}
+	return &showtime, nil
+}
+
+func CreateShowtime(req models.ShowtimeRequest) (*models.Showtime, error) {
+	format, err := showtimeFormat(req)
+	if err != nil {
+		return nil, err
+	}
+
+	showtime := models.Showtime{}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyShowtimeRequest(tx, &showtime, req, format); err != nil {
+			return err
+		}
+		if err := tx.Create(&showtime).Error; err != nil {
+			return err
+		}
+		return ensureInventory(tx, showtime.ID, showtime.StudioID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetShowtime(showtime.ID)
+}
+
+func UpdateShowtime(id uint, req models.ShowtimeRequest) (*models.Showtime, error) {
+	format, err := showtimeFormat(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var showtime models.Showtime
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&showtime, id).Error; err != nil {
+			return ErrShowtimeNotFound
+		}
+
+		previousStudioID := showtime.StudioID
+		if err := applyShowtimeRequest(tx, &showtime, req, format); err != nil {
+			return err
+		}
+		if showtime.StudioID != previousStudioID {
+			// The seats of the old studio mean nothing in the new one.
+			if err := clearInventory(tx, showtime.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Movie", "Studio").Save(&showtime).Error; err != nil {
+			return err
+		}
+		return ensureInventory(tx, showtime.ID, showtime.StudioID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetShowtime(showtime.ID)
+}
+
+func DeleteShowtime(id uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := clearInventory(tx, id); err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Showtime{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrShowtimeNotFound
+		}
+		return nil
+	})
+}
+
+// clearInventory drops the seat inventory of a showtime, refusing to do so
+// once any of its seats has been sold.
+func clearInventory(tx *gorm.DB, showtimeID uint) error {
+	var sold int64
+	err := tx.Model(&models.SeatInventory{}).
+		Where("showtime_id = ? AND status <> ?", showtimeID, models.SeatStatusAvailable).
+		Count(&sold).Error
+	if err != nil {
+		return err
+	}
+	if sold > 0 {
+		return ErrShowtimeHasSales
+	}
+	return tx.Where("showtime_id = ?", showtimeID).Delete(&models.SeatInventory{}).Error
+}
+
+// showtimeFormat checks the fields of req that need no lookup and returns
+// its format, 2D unless given.
+func showtimeFormat(req models.ShowtimeRequest) (string, error) {
+	if req.MovieID == 0 || req.StudioID == 0 || req.StartTime.IsZero() {
+		return "", ErrInvalidShowtime
+	}
+
+	format := strings.ToUpper(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = "2D"
+	}
+	if !validFormats[format] {
+		return "", ErrInvalidFormat
+	}
+	return format, nil
+}
+
+// applyShowtimeRequest copies req, checked by showtimeFormat, onto
+// showtime, deriving the end time from the movie runtime and rejecting
+// overlapping screenings. The studio stays locked for the rest of the
+// transaction, so two screenings scheduled at once cannot both pass the
+// overlap check.
+func applyShowtimeRequest(tx *gorm.DB, showtime *models.Showtime, req models.ShowtimeRequest, format string) error {
+	var movie models.Movie
+	if err := tx.First(&movie, req.MovieID).Error; err != nil {
+		return ErrMovieNotFound
+	}
+
+	var studio models.Studio
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&studio, req.StudioID).Error; err != nil {
+		return ErrStudioNotFound
+	}
+
+	start := req.StartTime
+	end := start.Add(time.Duration(movie.RuntimeMinutes) * time.Minute)
+	buffer := cleaningBuffer()
+
+	// Only screenings close enough to possibly collide need to be loaded.
+	var nearby []models.Showtime
+	query := tx.Where("studio_id = ? AND start_time < ? AND end_time > ?",
+		req.StudioID, end.Add(buffer), start.Add(-buffer))
+	if showtime.ID != 0 {
+		query = query.Where("id <> ?", showtime.ID)
+	}
+	if err := query.Find(&nearby).Error; err != nil {
+		return err
+	}
+	for _, other := range nearby {
+		if showtimesOverlap(start, end, other.StartTime, other.EndTime, buffer) {
+			return &ShowtimeConflictError{Conflict: other}
+		}
+	}
+
+	showtime.MovieID = movie.ID
+	showtime.StudioID = studio.ID
+	showtime.StartTime = start
+	showtime.EndTime = end
+	showtime.Format = format
+	return nil
+}
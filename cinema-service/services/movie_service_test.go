@@ -0,0 +1,89 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowtimesOverlap(t *testing.T) {
+	base := time.Date(2026, 10, 18, 19, 0, 0, 0, time.UTC)
+	buffer := 15 * time.Minute
+
+	tests := []struct {
+		name     string
+		bStart   time.Time
+		bEnd     time.Time
+		expected bool
+	}{
+		{
+			name:     "Same slot",
+			bStart:   base,
+			bEnd:     base.Add(2 * time.Hour),
+			expected: true,
+		},
+		{
+			name:     "Starts during screening",
+			bStart:   base.Add(time.Hour),
+			bEnd:     base.Add(3 * time.Hour),
+			expected: true,
+		},
+		{
+			name:     "Starts inside cleaning buffer",
+			bStart:   base.Add(2*time.Hour + 10*time.Minute),
+			bEnd:     base.Add(4 * time.Hour),
+			expected: true,
+		},
+		{
+			name:     "Starts right after cleaning buffer",
+			bStart:   base.Add(2*time.Hour + 15*time.Minute),
+			bEnd:     base.Add(4 * time.Hour),
+			expected: false,
+		},
+		{
+			name:     "Ends inside cleaning buffer before",
+			bStart:   base.Add(-2 * time.Hour),
+			bEnd:     base.Add(-5 * time.Minute),
+			expected: true,
+		},
+		{
+			name:     "Ends well before",
+			bStart:   base.Add(-3 * time.Hour),
+			bEnd:     base.Add(-time.Hour),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aEnd := base.Add(2 * time.Hour)
+			assert.Equal(t, tt.expected, showtimesOverlap(base, aEnd, tt.bStart, tt.bEnd, buffer))
+			assert.Equal(t, tt.expected, showtimesOverlap(tt.bStart, tt.bEnd, base, aEnd, buffer))
+		})
+	}
+}
+
+func TestCleaningBuffer(t *testing.T) {
+	defer os.Unsetenv("CLEANING_BUFFER_MINUTES")
+
+	os.Unsetenv("CLEANING_BUFFER_MINUTES")
+	assert.Equal(t, 15*time.Minute, cleaningBuffer())
+
+	os.Setenv("CLEANING_BUFFER_MINUTES", "30")
+	assert.Equal(t, 30*time.Minute, cleaningBuffer())
+
+	os.Setenv("CLEANING_BUFFER_MINUTES", "invalid")
+	assert.Equal(t, 15*time.Minute, cleaningBuffer())
+}
+
+func TestCreateMovieValidation(t *testing.T) {
+	_, err := CreateMovie(models.MovieRequest{Title: "", RuntimeMinutes: 120})
+	assert.ErrorIs(t, err, ErrInvalidMovie)
+
+	_, err = CreateMovie(models.MovieRequest{Title: "Dune", RuntimeMinutes: 0})
+	assert.ErrorIs(t, err, ErrInvalidMovie)
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"cinema-service/database"
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDatabase points the services at the database named by
+// TEST_DATABASE_URL, skipping the test when none is configured. The tests
+// here need PostgreSQL's row locks; the README shows how to run them
+// against the docker compose database.
+func openTestDatabase(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Studio{}, &models.Seat{}, &models.Movie{}, &models.Showtime{}, &models.SeatInventory{}, &models.Aisle{}))
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+}
+
+// createTestShowtime creates a studio with one row of seats and a showtime
+// with its inventory, removing them again when the test ends.
+func createTestShowtime(t *testing.T, seatCount int) (models.Showtime, []uint) {
+	db := database.DB
+	suffix := time.Now().UnixNano()
+
+	studio := models.Studio{Name: fmt.Sprintf("Stress %d", suffix), TotalSeats: seatCount, Rows: 1, Columns: seatCount}
+	require.NoError(t, db.Create(&studio).Error)
+
+	seatIDs := make([]uint, 0, seatCount)
+	for i := 1; i <= seatCount; i++ {
+		seat := models.Seat{StudioID: studio.ID, SeatNumber: fmt.Sprintf("A%d", i), RowLabel: "A", RowIndex: 1, ColumnIndex: i}
+		require.NoError(t, db.Create(&seat).Error)
+		seatIDs = append(seatIDs, seat.ID)
+	}
+
+	movie := models.Movie{Title: studio.Name, RuntimeMinutes: 90}
+	require.NoError(t, db.Create(&movie).Error)
+
+	start := time.Now().Add(24 * time.Hour)
+	showtime := models.Showtime{MovieID: movie.ID, StudioID: studio.ID, StartTime: start, EndTime: start.Add(90 * time.Minute)}
+	require.NoError(t, db.Create(&showtime).Error)
+	require.NoError(t, ensureInventory(db, showtime.ID, studio.ID))
+
+	t.Cleanup(func() {
+		db.Where("showtime_id = ?", showtime.ID).Delete(&models.SeatInventory{})
+		db.Unscoped().Delete(&showtime)
+		db.Unscoped().Delete(&movie)
+		db.Unscoped().Where("studio_id = ?", studio.ID).Delete(&models.Seat{})
+		db.Unscoped().Delete(&studio)
+	})
+
+	return showtime, seatIDs
+}
+
+func TestReserveSeatsConcurrently(t *testing.T) {
+	openTestDatabase(t)
+	showtime, seatIDs := createTestShowtime(t, 6)
+
+	// Every request overlaps its neighbours, so each seat is fought over by
+	// several goroutines at once.
+	const workers = 40
+	requests := make([][]uint, workers)
+	for i := range requests {
+		first := i % (len(seatIDs) - 1)
+		requests[i] = []uint{seatIDs[first], seatIDs[first+1]}
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		soldTo    = map[uint]int{}
+		succeeded int
+	)
+	start := make(chan struct{})
+	for i, seats := range requests {
+		wg.Add(1)
+		go func(worker int, seats []uint) {
+			defer wg.Done()
+			<-start
+
+			var err error
+			if worker%2 == 0 {
+				_, err = ReserveSeats(models.SeatReservationRequest{ShowtimeID: showtime.ID, SeatIDs: seats})
+			} else {
+				_, err = HoldSeats(models.SeatHoldRequest{ShowtimeID: showtime.ID, SeatIDs: seats})
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+				for _, id := range seats {
+					soldTo[id]++
+				}
+				return
+			}
+
+			var conflict *SeatConflictError
+			if assert.True(t, errors.As(err, &conflict), "unexpected error: %v", err) {
+				assert.NotEmpty(t, conflict.Seats)
+				assert.Subset(t, seats, conflict.SeatIDs())
+			}
+		}(i, seats)
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Greater(t, succeeded, 0)
+	for id, claims := range soldTo {
+		assert.Equal(t, 1, claims, "seat %d was claimed more than once", id)
+	}
+
+	var claimed int64
+	database.DB.Model(&models.SeatInventory{}).
+		Where("showtime_id = ? AND status <> ?", showtime.ID, models.SeatStatusAvailable).
+		Count(&claimed)
+	assert.Equal(t, int64(len(soldTo)), claimed)
+}
+
+func TestReserveSeatsReportsConflicts(t *testing.T) {
+	openTestDatabase(t)
+	showtime, seatIDs := createTestShowtime(t, 4)
+
+	_, err := ReserveSeats(models.SeatReservationRequest{ShowtimeID: showtime.ID, SeatIDs: seatIDs[1:3]})
+	require.NoError(t, err)
+
+	_, err = ReserveSeats(models.SeatReservationRequest{ShowtimeID: showtime.ID, SeatIDs: []uint{seatIDs[0], seatIDs[2], seatIDs[1]}})
+	var conflict *SeatConflictError
+	require.True(t, errors.As(err, &conflict))
+	assert.Equal(t, []models.SeatConflict{
+		{SeatID: seatIDs[2], SeatNumber: "A3", Status: models.SeatStatusSold},
+		{SeatID: seatIDs[1], SeatNumber: "A2", Status: models.SeatStatusSold},
+	}, conflict.Seats)
+
+	// The failed request must not have sold the free seat either.
+	var status string
+	database.DB.Model(&models.SeatInventory{}).
+		Where("showtime_id = ? AND seat_id = ?", showtime.ID, seatIDs[0]).
+		Pluck("status", &status)
+	assert.Equal(t, models.SeatStatusAvailable, status)
+}
+
+func TestReserveSeatsRejectsSeatsOfAnotherStudio(t *testing.T) {
+	openTestDatabase(t)
+	showtime, _ := createTestShowtime(t, 2)
+	other, otherSeatIDs := createTestShowtime(t, 2)
+
+	_, err := ReserveSeats(models.SeatReservationRequest{ShowtimeID: showtime.ID, SeatIDs: otherSeatIDs[:1]})
+	var selection *SeatSelectionError
+	require.True(t, errors.As(err, &selection))
+	assert.ErrorIs(t, err, ErrSeatsNotInStudio)
+	assert.Equal(t, otherSeatIDs[:1], selection.SeatIDs)
+
+	_, err = ReserveSeats(models.SeatReservationRequest{ShowtimeID: showtime.ID, StudioID: other.StudioID, SeatIDs: otherSeatIDs[:1]})
+	assert.ErrorIs(t, err, ErrShowtimeStudioMismatch)
+}
+
+func TestReserveSeatsAppliesGapRule(t *testing.T) {
+	openTestDatabase(t)
+	showtime, seatIDs := createTestShowtime(t, 3)
+	setGapRule := func(rule string) {
+		require.NoError(t, database.DB.Model(&models.Studio{}).Where("id = ?", showtime.StudioID).Update("gap_rule", rule).Error)
+	}
+
+	// Taking A1 and A2 strands A3 against the end of the row.
+	request := models.SeatReservationRequest{ShowtimeID: showtime.ID, SeatIDs: seatIDs[:2]}
+
+	setGapRule(models.GapRuleReject)
+	_, err := ReserveSeats(request)
+	var gapErr *SeatGapError
+	require.True(t, errors.As(err, &gapErr))
+	assert.Equal(t, []models.SeatGap{{SeatID: seatIDs[2], SeatNumber: "A3", Row: "A"}}, gapErr.Seats)
+
+	setGapRule(models.GapRuleWarn)
+	gaps, err := ReserveSeats(request)
+	require.NoError(t, err)
+	assert.Equal(t, gapErr.Seats, gaps)
+}
+
+func TestGapRuleHoldsConcurrently(t *testing.T) {
+	openTestDatabase(t)
+	showtime, seatIDs := createTestShowtime(t, 3)
+	require.NoError(t, database.DB.Model(&models.Studio{}).Where("id = ?", showtime.StudioID).Update("gap_rule", models.GapRuleReject).Error)
+
+	// A1 and A3 are fine on their own, but together they strand A2, so at
+	// most one of each pair of simultaneous holds may go through.
+	for round := 0; round < 10; round++ {
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			succeeded int
+		)
+		ready := make(chan struct{})
+		for _, seatID := range []uint{seatIDs[0], seatIDs[2]} {
+			wg.Add(1)
+			go func(seatID uint) {
+				defer wg.Done()
+				<-ready
+
+				_, err := HoldSeats(models.SeatHoldRequest{ShowtimeID: showtime.ID, SeatIDs: []uint{seatID}})
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil {
+					succeeded++
+					return
+				}
+				var gapErr *SeatGapError
+				assert.True(t, errors.As(err, &gapErr), "unexpected error: %v", err)
+			}(seatID)
+		}
+		close(ready)
+		wg.Wait()
+
+		assert.Equal(t, 1, succeeded)
+		require.NoError(t, database.DB.Model(&models.SeatInventory{}).
+			Where("showtime_id = ?", showtime.ID).
+			Updates(map[string]interface{}{"status": models.SeatStatusAvailable, "hold_token": "", "held_until": nil}).Error)
+	}
+}
+
+func TestCreateShowtimesConcurrently(t *testing.T) {
+	openTestDatabase(t)
+	existing, _ := createTestShowtime(t, 2)
+
+	// Every request starts a few minutes after the one before, so they all
+	// overlap and only one may be scheduled.
+	const workers = 10
+	start := existing.EndTime.Add(24 * time.Hour)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		scheduled []uint
+	)
+	ready := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-ready
+
+			showtime, err := CreateShowtime(models.ShowtimeRequest{
+				MovieID:   existing.MovieID,
+				StudioID:  existing.StudioID,
+				StartTime: start.Add(time.Duration(i) * 5 * time.Minute),
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				scheduled = append(scheduled, showtime.ID)
+				return
+			}
+			var conflict *ShowtimeConflictError
+			assert.True(t, errors.As(err, &conflict), "unexpected error: %v", err)
+		}(i)
+	}
+	close(ready)
+	wg.Wait()
+
+	t.Cleanup(func() {
+		for _, id := range scheduled {
+			database.DB.Where("showtime_id = ?", id).Delete(&models.SeatInventory{})
+			database.DB.Unscoped().Delete(&models.Showtime{}, id)
+		}
+	})
+	assert.Len(t, scheduled, 1)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"cinema-service/models"
+)
+
+// eventBuffer is how many events a subscriber may fall behind before it is
+// dropped. Dropped subscribers see their channel closed and are expected to
+// reconnect and reload the seat map.
+const eventBuffer = 64
+
+// showtimeBroker fans events out to the subscribers of each showtime. It
+// only reaches subscribers connected to this process.
+type showtimeBroker[T any] struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan T]struct{}
+}
+
+func newShowtimeBroker[T any]() *showtimeBroker[T] {
+	return &showtimeBroker[T]{subscribers: map[uint]map[chan T]struct{}{}}
+}
+
+var seatEvents = newShowtimeBroker[models.SeatEvent]()
+
+// SubscribeSeatEvents returns a channel of seat changes for a showtime and a
+// function that ends the subscription. The channel is closed when the
+// subscription ends or the subscriber falls too far behind.
+func SubscribeSeatEvents(showtimeID uint) (<-chan models.SeatEvent, func()) {
+	return seatEvents.subscribe(showtimeID)
+}
+
+func (b *showtimeBroker[T]) subscribe(showtimeID uint) (<-chan T, func()) {
+	ch := make(chan T, eventBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[showtimeID] == nil {
+		b.subscribers[showtimeID] = map[chan T]struct{}{}
+	}
+	b.subscribers[showtimeID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			b.remove(showtimeID, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// remove closes and forgets a subscriber. The caller holds b.mu.
+func (b *showtimeBroker[T]) remove(showtimeID uint, ch chan T) {
+	subscribers := b.subscribers[showtimeID]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+	delete(subscribers, ch)
+	close(ch)
+	if len(subscribers) == 0 {
+		delete(b.subscribers, showtimeID)
+	}
+}
+
+func (b *showtimeBroker[T]) publish(showtimeID uint, event T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[showtimeID] {
+		select {
+		case ch <- event:
+		default:
+			b.remove(showtimeID, ch)
+		}
+	}
+}
+
+// publishSeatChange announces that seats of one showtime moved to status.
+func publishSeatChange(showtimeID uint, seatIDs []uint, status string) {
+	if len(seatIDs) == 0 {
+		return
+	}
+	seatEvents.publish(showtimeID, models.SeatEvent{
+		ShowtimeID: showtimeID,
+		SeatIDs:    seatIDs,
+		Status:     status,
+		At:         time.Now(),
+	})
+}
+
+// publishInventoryChange announces a status change of inventory rows that
+// may span several showtimes.
+func publishInventoryChange(rows []models.SeatInventory, status string) {
+	byShowtime := map[uint][]uint{}
+	var showtimes []uint
+	for _, row := range rows {
+		if _, ok := byShowtime[row.ShowtimeID]; !ok {
+			showtimes = append(showtimes, row.ShowtimeID)
+		}
+		byShowtime[row.ShowtimeID] = append(byShowtime[row.ShowtimeID], row.SeatID)
+	}
+	for _, showtimeID := range showtimes {
+		publishSeatChange(showtimeID, byShowtime[showtimeID], status)
+	}
+}
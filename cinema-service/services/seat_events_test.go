@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeatEventBroker(t *testing.T) {
+	broker := newShowtimeBroker[models.SeatEvent]()
+
+	first, unsubscribeFirst := broker.subscribe(1)
+	second, unsubscribeSecond := broker.subscribe(1)
+	other, unsubscribeOther := broker.subscribe(2)
+	defer unsubscribeSecond()
+	defer unsubscribeOther()
+
+	broker.publish(1, models.SeatEvent{ShowtimeID: 1, SeatIDs: []uint{3}, Status: models.SeatStatusHeld})
+
+	assert.Equal(t, []uint{3}, (<-first).SeatIDs)
+	assert.Equal(t, models.SeatStatusHeld, (<-second).Status)
+	assert.Len(t, other, 0)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, open := <-first
+	assert.False(t, open)
+
+	broker.publish(1, models.SeatEvent{ShowtimeID: 1, SeatIDs: []uint{4}, Status: models.SeatStatusSold})
+	assert.Equal(t, []uint{4}, (<-second).SeatIDs)
+}
+
+func TestSeatEventBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := newShowtimeBroker[models.SeatEvent]()
+	events, unsubscribe := broker.subscribe(1)
+	defer unsubscribe()
+
+	for i := 0; i <= eventBuffer; i++ {
+		broker.publish(1, models.SeatEvent{ShowtimeID: 1, SeatIDs: []uint{uint(i)}})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, eventBuffer, received)
+	assert.Empty(t, broker.subscribers)
+}
+
+func TestPublishInventoryChangeGroupsByShowtime(t *testing.T) {
+	previous := seatEvents
+	seatEvents = newShowtimeBroker[models.SeatEvent]()
+	defer func() { seatEvents = previous }()
+
+	first, unsubscribeFirst := SubscribeSeatEvents(1)
+	second, unsubscribeSecond := SubscribeSeatEvents(2)
+	defer unsubscribeFirst()
+	defer unsubscribeSecond()
+
+	publishInventoryChange([]models.SeatInventory{
+		{ShowtimeID: 1, SeatID: 10},
+		{ShowtimeID: 2, SeatID: 20},
+		{ShowtimeID: 1, SeatID: 11},
+	}, models.SeatStatusAvailable)
+
+	event := <-first
+	assert.Equal(t, []uint{10, 11}, event.SeatIDs)
+	assert.Equal(t, models.SeatStatusAvailable, event.Status)
+	assert.Equal(t, []uint{20}, (<-second).SeatIDs)
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"cinema-service/models"
+)
+
+var ErrTooManySeatsSelected = errors.New("at most 10 seats can be selected at once")
+
+const maxSelectedSeats = 10
+
+// selectionTTL is how long a tentative selection soft-locks its seats
+// unless the client repeats it.
+func selectionTTL() time.Duration {
+	if v := os.Getenv("SEAT_SELECTION_TTL_SECONDS"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 30 * time.Second
+}
+
+// softLock marks a seat as being selected by one client. Soft locks only
+// steer other clients away from the seat; holding seats is what actually
+// sets them aside.
+type softLock struct {
+	owner     string
+	expiresAt time.Time
+}
+
+// selectionRegistry keeps the soft locks of every showtime in memory and
+// announces changes to them.
+type selectionRegistry struct {
+	mu     sync.Mutex
+	locks  map[uint]map[uint]softLock
+	events *showtimeBroker[models.SeatSelectionEvent]
+	now    func() time.Time
+}
+
+func newSelectionRegistry() *selectionRegistry {
+	return &selectionRegistry{
+		locks:  map[uint]map[uint]softLock{},
+		events: newShowtimeBroker[models.SeatSelectionEvent](),
+		now:    time.Now,
+	}
+}
+
+var seatSelections = newSelectionRegistry()
+
+// NewSelectionOwner returns an identifier for one seat selection client.
+func NewSelectionOwner() (string, error) {
+	return newHoldToken()
+}
+
+// SelectSeats makes seatIDs the tentative selection of owner for a
+// showtime. Seats someone else is selecting are left out and reported as
+// rejected; seats owner selected before but no longer wants are let go.
+func SelectSeats(showtimeID uint, owner string, seatIDs []uint) (models.SeatSelection, error) {
+	return seatSelections.selectSeats(showtimeID, owner, seatIDs)
+}
+
+// ClearSelection lets go of every seat owner is selecting for a showtime.
+func ClearSelection(showtimeID uint, owner string) {
+	seatSelections.clear(showtimeID, owner)
+}
+
+// SelectedByOthers lists the seats of a showtime that clients other than
+// owner are selecting.
+func SelectedByOthers(showtimeID uint, owner string) []uint {
+	return seatSelections.selectedByOthers(showtimeID, owner)
+}
+
+// SubscribeSelectionEvents returns a channel of selection changes for a
+// showtime and a function that ends the subscription.
+func SubscribeSelectionEvents(showtimeID uint) (<-chan models.SeatSelectionEvent, func()) {
+	return seatSelections.events.subscribe(showtimeID)
+}
+
+// ReleaseExpiredSelections lets go of soft locks that were not renewed in
+// time and returns how many seats were freed.
+func ReleaseExpiredSelections() int {
+	return seatSelections.releaseExpired()
+}
+
+// StartSelectionSweeper releases expired soft locks every interval until
+// the process exits.
+func StartSelectionSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if released := ReleaseExpiredSelections(); released > 0 {
+				log.Printf("Released %d seats from expired selections", released)
+			}
+		}
+	}()
+}
+
+func (r *selectionRegistry) selectSeats(showtimeID uint, owner string, seatIDs []uint) (models.SeatSelection, error) {
+	wanted := make(map[uint]bool, len(seatIDs))
+	for _, id := range seatIDs {
+		wanted[id] = true
+	}
+	if len(wanted) > maxSelectedSeats {
+		return models.SeatSelection{}, ErrTooManySeatsSelected
+	}
+
+	now := r.now()
+	selection := models.SeatSelection{SeatIDs: []uint{}, ExpiresAt: now.Add(selectionTTL())}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locks := r.locks[showtimeID]
+	if locks == nil {
+		locks = map[uint]softLock{}
+		r.locks[showtimeID] = locks
+	}
+
+	var added []uint
+	for _, id := range seatIDs {
+		if !wanted[id] {
+			continue // repeated in the request
+		}
+		delete(wanted, id)
+
+		lock, ok := locks[id]
+		if ok && lock.owner != owner && lock.expiresAt.After(now) {
+			selection.Rejected = append(selection.Rejected, id)
+			continue
+		}
+		if !ok || lock.owner != owner {
+			added = append(added, id)
+		}
+		locks[id] = softLock{owner: owner, expiresAt: selection.ExpiresAt}
+		selection.SeatIDs = append(selection.SeatIDs, id)
+	}
+
+	kept := make(map[uint]bool, len(selection.SeatIDs))
+	for _, id := range selection.SeatIDs {
+		kept[id] = true
+	}
+	var removed []uint
+	for id, lock := range locks {
+		if lock.owner == owner && !kept[id] {
+			delete(locks, id)
+			removed = append(removed, id)
+		}
+	}
+	if len(locks) == 0 {
+		delete(r.locks, showtimeID)
+	}
+
+	r.announce(showtimeID, owner, added, true)
+	r.announce(showtimeID, owner, removed, false)
+	return selection, nil
+}
+
+func (r *selectionRegistry) clear(showtimeID uint, owner string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locks := r.locks[showtimeID]
+	var removed []uint
+	for id, lock := range locks {
+		if lock.owner == owner {
+			delete(locks, id)
+			removed = append(removed, id)
+		}
+	}
+	if len(locks) == 0 {
+		delete(r.locks, showtimeID)
+	}
+	r.announce(showtimeID, owner, removed, false)
+}
+
+func (r *selectionRegistry) selectedByOthers(showtimeID uint, owner string) []uint {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	selected := []uint{}
+	for id, lock := range r.locks[showtimeID] {
+		if lock.owner != owner && lock.expiresAt.After(now) {
+			selected = append(selected, id)
+		}
+	}
+	sort.Slice(selected, func(a, b int) bool { return selected[a] < selected[b] })
+	return selected
+}
+
+func (r *selectionRegistry) releaseExpired() int {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	released := 0
+	for showtimeID, locks := range r.locks {
+		expired := map[string][]uint{}
+		for id, lock := range locks {
+			if !lock.expiresAt.After(now) {
+				delete(locks, id)
+				expired[lock.owner] = append(expired[lock.owner], id)
+				released++
+			}
+		}
+		if len(locks) == 0 {
+			delete(r.locks, showtimeID)
+		}
+		for owner, seatIDs := range expired {
+			r.announce(showtimeID, owner, seatIDs, false)
+		}
+	}
+	return released
+}
+
+// announce publishes a selection change. The caller holds r.mu, which keeps
+// the events of one showtime in order.
+func (r *selectionRegistry) announce(showtimeID uint, owner string, seatIDs []uint, selecting bool) {
+	if len(seatIDs) == 0 {
+		return
+	}
+	sort.Slice(seatIDs, func(a, b int) bool { return seatIDs[a] < seatIDs[b] })
+	r.events.publish(showtimeID, models.SeatSelectionEvent{
+		ShowtimeID: showtimeID,
+		SeatIDs:    seatIDs,
+		Selecting:  selecting,
+		Owner:      owner,
+	})
+}
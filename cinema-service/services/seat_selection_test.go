@@ -0,0 +1,100 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSelectionRegistry(now *time.Time) *selectionRegistry {
+	r := newSelectionRegistry()
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestSelectSeatsSoftLocks(t *testing.T) {
+	now := time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC)
+	r := newTestSelectionRegistry(&now)
+	events, unsubscribe := r.events.subscribe(1)
+	defer unsubscribe()
+
+	first, err := r.selectSeats(1, "alice", []uint{3, 4, 4})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 4}, first.SeatIDs)
+	assert.Empty(t, first.Rejected)
+	assert.Equal(t, now.Add(selectionTTL()), first.ExpiresAt)
+
+	event := <-events
+	assert.True(t, event.Selecting)
+	assert.Equal(t, "alice", event.Owner)
+	assert.Equal(t, []uint{3, 4}, event.SeatIDs)
+
+	second, err := r.selectSeats(1, "bob", []uint{4, 5})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{5}, second.SeatIDs)
+	assert.Equal(t, []uint{4}, second.Rejected)
+	assert.Equal(t, []uint{5}, (<-events).SeatIDs)
+
+	assert.Equal(t, []uint{3, 4}, r.selectedByOthers(1, "bob"))
+	assert.Equal(t, []uint{5}, r.selectedByOthers(1, "alice"))
+	assert.Empty(t, r.selectedByOthers(2, "alice"))
+
+	// Narrowing a selection lets go of the seats left out.
+	_, err = r.selectSeats(1, "alice", []uint{4})
+	assert.NoError(t, err)
+	event = <-events
+	assert.False(t, event.Selecting)
+	assert.Equal(t, []uint{3}, event.SeatIDs)
+	assert.Len(t, events, 0)
+
+	r.clear(1, "alice")
+	assert.Equal(t, []uint{4}, (<-events).SeatIDs)
+	assert.Empty(t, r.selectedByOthers(1, "bob"))
+}
+
+func TestSelectSeatsLimit(t *testing.T) {
+	now := time.Now()
+	r := newTestSelectionRegistry(&now)
+
+	seatIDs := make([]uint, maxSelectedSeats+1)
+	for i := range seatIDs {
+		seatIDs[i] = uint(i + 1)
+	}
+	_, err := r.selectSeats(1, "alice", seatIDs)
+	assert.ErrorIs(t, err, ErrTooManySeatsSelected)
+
+	selection, err := r.selectSeats(1, "alice", seatIDs[:maxSelectedSeats])
+	assert.NoError(t, err)
+	assert.Len(t, selection.SeatIDs, maxSelectedSeats)
+}
+
+func TestExpiredSoftLocks(t *testing.T) {
+	now := time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC)
+	r := newTestSelectionRegistry(&now)
+
+	_, err := r.selectSeats(1, "alice", []uint{3, 4})
+	assert.NoError(t, err)
+
+	now = now.Add(selectionTTL())
+	assert.Empty(t, r.selectedByOthers(1, "bob"))
+
+	// An expired soft lock no longer stands in anyone's way.
+	selection, err := r.selectSeats(1, "bob", []uint{4})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{4}, selection.SeatIDs)
+	assert.Empty(t, selection.Rejected)
+
+	events, unsubscribe := r.events.subscribe(1)
+	defer unsubscribe()
+
+	assert.Equal(t, 1, r.releaseExpired())
+	event := <-events
+	assert.False(t, event.Selecting)
+	assert.Equal(t, "alice", event.Owner)
+	assert.Equal(t, []uint{3}, event.SeatIDs)
+
+	now = now.Add(selectionTTL())
+	assert.Equal(t, 1, r.releaseExpired())
+	assert.Empty(t, r.locks)
+}
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cinema-service/database"
+	"cinema-service/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrStudioNameRequired = errors.New("name is required")
+	ErrStudioHasShowtimes = errors.New("studio still has upcoming showtimes")
+	ErrSeatsInUse         = errors.New("seats being removed or moved are held or sold for upcoming showtimes")
+	ErrSeatNotFound       = errors.New("seat not found")
+)
+
+var validSeatTypes = map[string]bool{
+	models.SeatTypeStandard:   true,
+	models.SeatTypePremium:    true,
+	models.SeatTypeWheelchair: true,
+	models.SeatTypeCompanion:  true,
+	models.SeatTypeCouch:      true,
+}
+
+// LayoutValidationError lists everything wrong with a submitted layout.
+type LayoutValidationError struct {
+	Problems []string
+}
+
+func (e *LayoutValidationError) Error() string {
+	return "invalid layout: " + strings.Join(e.Problems, "; ")
+}
+
+// rowLabel turns a 1-based row index into a letter label: 1 is "A", 26 is
+// "Z" and 27 is "AA".
+func rowLabel(index int) string {
+	label := ""
+	for index > 0 {
+		index--
+		label = string(rune('A'+index%26)) + label
+		index /= 26
+	}
+	return label
+}
+
+// plannedLayout is a validated layout ready to be written to the database.
+type plannedLayout struct {
+	Seats        []models.Seat
+	Pairs        map[string]string
+	AisleColumns []int
+	Rows         int
+	Columns      int
+}
+
+// planLayout validates req and works out seat numbers, couch pairs and the
+// grid size. Adjacent couch seats in a row pair up from the left.
+func planLayout(req models.StudioLayoutRequest) (*plannedLayout, error) {
+	var problems []string
+	plan := &plannedLayout{Pairs: map[string]string{}}
+
+	if len(req.Seats) == 0 {
+		problems = append(problems, "layout needs at least one seat")
+	}
+
+	aisles := map[int]bool{}
+	for _, col := range req.AisleColumns {
+		if col < 1 {
+			problems = append(problems, fmt.Sprintf("aisle column %d must be positive", col))
+			continue
+		}
+		if !aisles[col] {
+			aisles[col] = true
+			plan.AisleColumns = append(plan.AisleColumns, col)
+		}
+		if col > plan.Columns {
+			plan.Columns = col
+		}
+	}
+	sort.Ints(plan.AisleColumns)
+
+	rows := map[int][]models.SeatLayoutInput{}
+	labels := map[int]string{}
+	positions := map[[2]int]bool{}
+	for i, input := range req.Seats {
+		where := fmt.Sprintf("seat %d", i+1)
+		if input.RowIndex < 1 || input.Column < 1 {
+			problems = append(problems, where+": rowIndex and column must be positive")
+			continue
+		}
+		where = fmt.Sprintf("seat at row %d, column %d", input.RowIndex, input.Column)
+
+		if input.SeatType == "" {
+			input.SeatType = models.SeatTypeStandard
+		}
+		if !validSeatTypes[input.SeatType] {
+			problems = append(problems, fmt.Sprintf("%s: unknown seat type %q", where, input.SeatType))
+		}
+		if aisles[input.Column] {
+			problems = append(problems, where+": column is an aisle")
+		}
+		if positions[[2]int{input.RowIndex, input.Column}] {
+			problems = append(problems, where+": position is used twice")
+		}
+		positions[[2]int{input.RowIndex, input.Column}] = true
+
+		if input.Row == "" {
+			input.Row = rowLabel(input.RowIndex)
+		}
+		if label, ok := labels[input.RowIndex]; ok && label != input.Row {
+			problems = append(problems, fmt.Sprintf("%s: row is labelled both %q and %q", where, label, input.Row))
+		}
+		labels[input.RowIndex] = input.Row
+
+		rows[input.RowIndex] = append(rows[input.RowIndex], input)
+		if input.RowIndex > plan.Rows {
+			plan.Rows = input.RowIndex
+		}
+		if input.Column > plan.Columns {
+			plan.Columns = input.Column
+		}
+	}
+
+	rowIndexes := make([]int, 0, len(rows))
+	for index := range rows {
+		rowIndexes = append(rowIndexes, index)
+	}
+	sort.Ints(rowIndexes)
+
+	numbers := map[string]bool{}
+	for _, index := range rowIndexes {
+		row := rows[index]
+		sort.Slice(row, func(a, b int) bool { return row[a].Column < row[b].Column })
+
+		for position, input := range row {
+			number := input.SeatNumber
+			if number == "" {
+				number = fmt.Sprintf("%s%d", input.Row, position+1)
+			}
+			if numbers[number] {
+				problems = append(problems, fmt.Sprintf("seat number %s is used twice", number))
+			}
+			numbers[number] = true
+
+			plan.Seats = append(plan.Seats, models.Seat{
+				SeatNumber:  number,
+				RowLabel:    input.Row,
+				RowIndex:    input.RowIndex,
+				ColumnIndex: input.Column,
+				SeatType:    input.SeatType,
+			})
+		}
+	}
+
+	// Pair couch seats sitting side by side
+	for i := 0; i < len(plan.Seats); i++ {
+		seat := plan.Seats[i]
+		if seat.SeatType != models.SeatTypeCouch {
+			continue
+		}
+		if i+1 < len(plan.Seats) {
+			next := plan.Seats[i+1]
+			if next.SeatType == models.SeatTypeCouch && next.RowIndex == seat.RowIndex && next.ColumnIndex == seat.ColumnIndex+1 {
+				plan.Pairs[seat.SeatNumber] = next.SeatNumber
+				plan.Pairs[next.SeatNumber] = seat.SeatNumber
+				i++
+				continue
+			}
+		}
+		problems = append(problems, fmt.Sprintf("couch seat %s has no adjacent couch seat to pair with", seat.SeatNumber))
+	}
+
+	if len(problems) > 0 {
+		return nil, &LayoutValidationError{Problems: problems}
+	}
+	return plan, nil
+}
+
+func CreateStudio(req models.StudioRequest) (*models.Studio, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrStudioNameRequired
+	}
+	gapRule := req.GapRule
+	if gapRule == "" {
+		gapRule = models.GapRuleOff
+	}
+	if !validGapRules[gapRule] {
+		return nil, ErrInvalidGapRule
+	}
+
+	var plan *plannedLayout
+	if req.Layout != nil {
+		var err error
+		if plan, err = planLayout(*req.Layout); err != nil {
+			return nil, err
+		}
+	}
+
+	studio := models.Studio{Name: name, GapRule: gapRule}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&studio).Error; err != nil {
+			return err
+		}
+		if plan == nil {
+			return nil
+		}
+		return applyLayout(tx, &studio, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &studio, nil
+}
+
+// UpdateStudio renames a studio and, when req names one, changes its gap
+// rule. The layout is replaced separately.
+func UpdateStudio(id uint, req models.StudioRequest) (*models.Studio, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrStudioNameRequired
+	}
+	if req.GapRule != "" && !validGapRules[req.GapRule] {
+		return nil, ErrInvalidGapRule
+	}
+
+	var studio models.Studio
+	if err := database.DB.First(&studio, id).Error; err != nil {
+		return nil, ErrStudioNotFound
+	}
+
+	updates := map[string]interface{}{"name": name}
+	if req.GapRule != "" {
+		updates["gap_rule"] = req.GapRule
+	}
+	if err := database.DB.Model(&studio).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &studio, nil
+}
+
+// ReplaceStudioLayout swaps the seat map of a studio for req. Seats whose
+// number survives keep their ID, and with it their sales; seats that
+// disappear or move to another place must not be held or sold for any
+// upcoming showtime.
+func ReplaceStudioLayout(id uint, req models.StudioLayoutRequest) (*models.Studio, error) {
+	plan, err := planLayout(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var studio models.Studio
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the studio keeps showtimes from being scheduled in it
+		// while its seats change.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&studio, id).Error; err != nil {
+			return ErrStudioNotFound
+		}
+		return applyLayout(tx, &studio, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &studio, nil
+}
+
+// changedSeats finds the live seats of a studio a new layout drops and
+// the ones it keeps by number but puts in another row or column.
+func changedSeats(existing []models.Seat, plan *plannedLayout) (removed, moved []uint) {
+	planned := map[string]models.Seat{}
+	for _, seat := range plan.Seats {
+		planned[seat.SeatNumber] = seat
+	}
+	for _, seat := range existing {
+		if seat.DeletedAt.Valid {
+			continue
+		}
+		next, ok := planned[seat.SeatNumber]
+		switch {
+		case !ok:
+			removed = append(removed, seat.ID)
+		case next.RowLabel != seat.RowLabel || next.RowIndex != seat.RowIndex || next.ColumnIndex != seat.ColumnIndex:
+			moved = append(moved, seat.ID)
+		}
+	}
+	return removed, moved
+}
+
+func applyLayout(tx *gorm.DB, studio *models.Studio, plan *plannedLayout) error {
+	var existing []models.Seat
+	if err := tx.Unscoped().Where("studio_id = ?", studio.ID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byNumber := map[string]models.Seat{}
+	for _, seat := range existing {
+		byNumber[seat.SeatNumber] = seat
+	}
+	removed, moved := changedSeats(existing, plan)
+
+	var upcoming []uint
+	err := tx.Model(&models.Showtime{}).
+		Where("studio_id = ? AND end_time > ?", studio.ID, time.Now()).
+		Pluck("id", &upcoming).Error
+	if err != nil {
+		return err
+	}
+
+	// A ticket for a moved seat would send its holder to another place.
+	// The inventory rows are locked in the order claimSeats locks them, so
+	// a hold cannot land on them between this check and the change.
+	changed := append(append([]uint{}, removed...), moved...)
+	if len(changed) > 0 && len(upcoming) > 0 {
+		var rows []models.SeatInventory
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("showtime_id IN ? AND seat_id IN ?", upcoming, changed).
+			Order("showtime_id, seat_id").
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if row.Status != models.SeatStatusAvailable {
+				return ErrSeatsInUse
+			}
+		}
+	}
+	if len(removed) > 0 && len(upcoming) > 0 {
+		if err := tx.Where("showtime_id IN ? AND seat_id IN ?", upcoming, removed).Delete(&models.SeatInventory{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Where("id IN ?", removed).Delete(&models.Seat{}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Create new seats, and move or restore the ones that keep their number
+	ids := map[string]uint{}
+	for _, seat := range plan.Seats {
+		seat.StudioID = studio.ID
+		if old, ok := byNumber[seat.SeatNumber]; ok {
+			err := tx.Unscoped().Model(&models.Seat{}).Where("id = ?", old.ID).Updates(map[string]interface{}{
+				"row_label":    seat.RowLabel,
+				"row_index":    seat.RowIndex,
+				"column_index": seat.ColumnIndex,
+				"seat_type":    seat.SeatType,
+				"pair_seat_id": nil,
+				"deleted_at":   nil,
+			}).Error
+			if err != nil {
+				return err
+			}
+			ids[seat.SeatNumber] = old.ID
+			continue
+		}
+		if err := tx.Create(&seat).Error; err != nil {
+			return err
+		}
+		ids[seat.SeatNumber] = seat.ID
+	}
+
+	for number, partner := range plan.Pairs {
+		if err := tx.Model(&models.Seat{}).Where("id = ?", ids[number]).Update("pair_seat_id", ids[partner]).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("studio_id = ?", studio.ID).Delete(&models.Aisle{}).Error; err != nil {
+		return err
+	}
+	for _, col := range plan.AisleColumns {
+		if err := tx.Create(&models.Aisle{StudioID: studio.ID, Column: col}).Error; err != nil {
+			return err
+		}
+	}
+
+	studio.Rows = plan.Rows
+	studio.Columns = plan.Columns
+	studio.TotalSeats = len(plan.Seats)
+	err = tx.Model(studio).Updates(map[string]interface{}{
+		"rows":        studio.Rows,
+		"columns":     studio.Columns,
+		"total_seats": studio.TotalSeats,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	for _, showtimeID := range upcoming {
+		if err := ensureInventory(tx, showtimeID, studio.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteStudio retires a studio and its seats. Past showtimes keep pointing
+// at the soft-deleted rows.
+func DeleteStudio(id uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var studio models.Studio
+		if err := tx.First(&studio, id).Error; err != nil {
+			return ErrStudioNotFound
+		}
+
+		var upcoming int64
+		err := tx.Model(&models.Showtime{}).
+			Where("studio_id = ? AND end_time > ?", id, time.Now()).
+			Count(&upcoming).Error
+		if err != nil {
+			return err
+		}
+		if upcoming > 0 {
+			return ErrStudioHasShowtimes
+		}
+
+		if err := tx.Where("studio_id = ?", id).Delete(&models.Seat{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&studio).Error
+	})
+}
+
+// SetSeatMaintenance takes a seat out of service or puts it back. Seats out
+// of service cannot be held or sold but still count towards TotalSeats.
+func SetSeatMaintenance(seatID uint, req models.SeatMaintenanceRequest) (*models.Seat, error) {
+	var seat models.Seat
+	if err := database.DB.First(&seat, seatID).Error; err != nil {
+		return nil, ErrSeatNotFound
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if !req.OutOfService {
+		note = ""
+	}
+	err := database.DB.Model(&seat).Updates(map[string]interface{}{
+		"out_of_service":   req.OutOfService,
+		"maintenance_note": note,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &seat, nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRowLabel(t *testing.T) {
+	assert.Equal(t, "A", rowLabel(1))
+	assert.Equal(t, "Z", rowLabel(26))
+	assert.Equal(t, "AA", rowLabel(27))
+	assert.Equal(t, "AZ", rowLabel(52))
+	assert.Equal(t, "BA", rowLabel(53))
+}
+
+func TestPlanLayout(t *testing.T) {
+	req := models.StudioLayoutRequest{
+		AisleColumns: []int{3},
+		Seats: []models.SeatLayoutInput{
+			{RowIndex: 1, Column: 2},
+			{RowIndex: 1, Column: 1, SeatType: models.SeatTypeWheelchair},
+			{RowIndex: 1, Column: 4, SeatType: models.SeatTypePremium},
+			{RowIndex: 2, Column: 1, SeatType: models.SeatTypeCouch},
+			{RowIndex: 2, Column: 2, SeatType: models.SeatTypeCouch},
+			{RowIndex: 2, Column: 5, SeatNumber: "VIP"},
+		},
+	}
+
+	plan, err := planLayout(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, plan.Rows)
+	assert.Equal(t, 5, plan.Columns)
+	assert.Equal(t, []int{3}, plan.AisleColumns)
+
+	numbers := []string{}
+	for _, seat := range plan.Seats {
+		numbers = append(numbers, seat.SeatNumber)
+	}
+	assert.Equal(t, []string{"A1", "A2", "A3", "B1", "B2", "VIP"}, numbers)
+	assert.Equal(t, models.SeatTypeWheelchair, plan.Seats[0].SeatType)
+	assert.Equal(t, models.SeatTypeStandard, plan.Seats[1].SeatType)
+	assert.Equal(t, "B", plan.Seats[3].RowLabel)
+	assert.Equal(t, map[string]string{"B1": "B2", "B2": "B1"}, plan.Pairs)
+}
+
+func TestPlanLayoutRejectsInvalidSeats(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.StudioLayoutRequest
+		problem string
+	}{
+		{
+			name:    "No seats",
+			req:     models.StudioLayoutRequest{},
+			problem: "layout needs at least one seat",
+		},
+		{
+			name: "Seat on aisle",
+			req: models.StudioLayoutRequest{
+				AisleColumns: []int{2},
+				Seats:        []models.SeatLayoutInput{{RowIndex: 1, Column: 2}},
+			},
+			problem: "seat at row 1, column 2: column is an aisle",
+		},
+		{
+			name: "Same position twice",
+			req: models.StudioLayoutRequest{
+				Seats: []models.SeatLayoutInput{{RowIndex: 1, Column: 1}, {RowIndex: 1, Column: 1}},
+			},
+			problem: "seat at row 1, column 1: position is used twice",
+		},
+		{
+			name: "Unknown seat type",
+			req: models.StudioLayoutRequest{
+				Seats: []models.SeatLayoutInput{{RowIndex: 1, Column: 1, SeatType: "recliner"}},
+			},
+			problem: `seat at row 1, column 1: unknown seat type "recliner"`,
+		},
+		{
+			name: "Lonely couch",
+			req: models.StudioLayoutRequest{
+				Seats: []models.SeatLayoutInput{
+					{RowIndex: 1, Column: 1, SeatType: models.SeatTypeCouch},
+					{RowIndex: 1, Column: 3, SeatType: models.SeatTypeCouch},
+				},
+			},
+			problem: "couch seat A1 has no adjacent couch seat to pair with",
+		},
+		{
+			name: "Duplicate seat number",
+			req: models.StudioLayoutRequest{
+				Seats: []models.SeatLayoutInput{
+					{RowIndex: 1, Column: 1, SeatNumber: "X"},
+					{RowIndex: 2, Column: 1, SeatNumber: "X"},
+				},
+			},
+			problem: "seat number X is used twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planLayout(tt.req)
+
+			var invalid *LayoutValidationError
+			assert.True(t, errors.As(err, &invalid))
+			assert.Contains(t, invalid.Problems, tt.problem)
+		})
+	}
+}
+
+func TestChangedSeats(t *testing.T) {
+	existing := []models.Seat{
+		{ID: 1, SeatNumber: "A1", RowLabel: "A", RowIndex: 1, ColumnIndex: 1},
+		{ID: 2, SeatNumber: "A2", RowLabel: "A", RowIndex: 1, ColumnIndex: 2},
+		{ID: 3, SeatNumber: "A3", RowLabel: "A", RowIndex: 1, ColumnIndex: 3},
+		{ID: 4, SeatNumber: "B1", RowLabel: "B", RowIndex: 2, ColumnIndex: 1},
+		{ID: 5, SeatNumber: "B2", RowLabel: "B", RowIndex: 2, ColumnIndex: 2, DeletedAt: gorm.DeletedAt{Valid: true}},
+	}
+	plan := &plannedLayout{Seats: []models.Seat{
+		{SeatNumber: "A1", RowLabel: "A", RowIndex: 1, ColumnIndex: 1, SeatType: models.SeatTypePremium},
+		{SeatNumber: "A2", RowLabel: "A", RowIndex: 1, ColumnIndex: 4},
+		{SeatNumber: "B1", RowLabel: "C", RowIndex: 3, ColumnIndex: 1},
+		{SeatNumber: "B2", RowLabel: "B", RowIndex: 2, ColumnIndex: 5},
+	}}
+
+	removed, moved := changedSeats(existing, plan)
+	assert.Equal(t, []uint{3}, removed)
+	assert.Equal(t, []uint{2, 4}, moved)
+}
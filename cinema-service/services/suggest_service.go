@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"cinema-service/models"
+)
+
+var (
+	ErrInvalidSeatCount = errors.New("count must be between 1 and 10")
+	ErrInvalidSeatType  = errors.New("unknown seat type")
+	ErrNoSeatBlock      = errors.New("no block of adjacent seats is available")
+)
+
+const (
+	maxSuggestedSeats = 10
+	maxSuggestions    = 3
+
+	// idealRowFraction places the best row about two thirds of the way
+	// from the screen to the back wall.
+	idealRowFraction = 0.65
+
+	// orphanPenalty outweighs any difference in position, so a block that
+	// strands a single seat is only offered when nothing else fits.
+	orphanPenalty = 2.0
+)
+
+// SuggestSeats finds the best blocks of count adjacent available seats for
+// a showtime. When seatType is empty, wheelchair and companion spaces are
+// left for the customers who need them.
+func SuggestSeats(showtimeID uint, count int, seatType string) ([]models.SeatSuggestion, error) {
+	if count < 1 || count > maxSuggestedSeats {
+		return nil, ErrInvalidSeatCount
+	}
+	if seatType != "" && !validSeatTypes[seatType] {
+		return nil, ErrInvalidSeatType
+	}
+
+	showtime, err := GetShowtime(showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := GetStudioLayout(showtime.StudioID, showtimeID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := suggestSeatBlocks(*layout, count, seatType, maxSuggestions)
+	if len(suggestions) == 0 {
+		return nil, ErrNoSeatBlock
+	}
+	return suggestions, nil
+}
+
+// suggestSeatBlocks scores every block of count adjacent available seats in
+// the layout and returns the best limit of them. Blocks never span an aisle
+// or gap and never split a couch pair.
+func suggestSeatBlocks(layout models.StudioLayout, count int, seatType string, limit int) []models.SeatSuggestion {
+	var suggestions []models.SeatSuggestion
+
+	for _, row := range layout.Rows {
+		for _, run := range seatRuns(row) {
+			for start := 0; start+count <= len(run); start++ {
+				block := run[start : start+count]
+				if !blockFits(block, seatType) {
+					continue
+				}
+
+				orphans := orphanSeats(run, start, count)
+				suggestion := models.SeatSuggestion{
+					Row:         row.Label,
+					OrphanSeats: orphans,
+					Score:       scoreBlock(layout, row.Index, block, orphans),
+				}
+				for _, seat := range block {
+					suggestion.SeatIDs = append(suggestion.SeatIDs, seat.ID)
+					suggestion.SeatNumbers = append(suggestion.SeatNumbers, seat.SeatNumber)
+				}
+				suggestions = append(suggestions, suggestion)
+			}
+		}
+	}
+
+	sort.SliceStable(suggestions, func(a, b int) bool {
+		return suggestions[a].Score < suggestions[b].Score
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// seatRuns splits a row into runs of seats standing side by side.
+func seatRuns(row models.LayoutRow) [][]*models.Seat {
+	var runs [][]*models.Seat
+	var current []*models.Seat
+	for _, cell := range row.Cells {
+		if cell.Kind == models.CellSeat && cell.Seat != nil {
+			current = append(current, cell.Seat)
+			continue
+		}
+		if len(current) > 0 {
+			runs = append(runs, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		runs = append(runs, current)
+	}
+	return runs
+}
+
+// blockFits reports whether every seat of the block can be offered: it is
+// available, of the wanted type and, if it is a couch seat, sold together
+// with its partner.
+func blockFits(block []*models.Seat, seatType string) bool {
+	inBlock := make(map[uint]bool, len(block))
+	for _, seat := range block {
+		inBlock[seat.ID] = true
+	}
+
+	for _, seat := range block {
+		if !seat.IsAvailable {
+			return false
+		}
+		switch {
+		case seatType != "" && seat.SeatType != seatType:
+			return false
+		case seatType == "" && (seat.SeatType == models.SeatTypeWheelchair || seat.SeatType == models.SeatTypeCompanion):
+			return false
+		}
+		if seat.PairSeatID != nil && !inBlock[*seat.PairSeatID] {
+			return false
+		}
+	}
+	return true
+}
+
+// orphanSeats counts the single available seats the block would strand at
+// either end, between itself and a taken seat or the end of the run.
+func orphanSeats(run []*models.Seat, start, count int) int {
+	free := func(i int) bool {
+		return i >= 0 && i < len(run) && run[i].IsAvailable
+	}
+
+	orphans := 0
+	if left := start - 1; free(left) && !free(left-1) {
+		orphans++
+	}
+	if right := start + count; free(right) && !free(right+1) {
+		orphans++
+	}
+	return orphans
+}
+
+// scoreBlock rates a block by how far it sits from the middle of the room
+// horizontally and from the ideal row, plus a penalty per orphaned seat.
+func scoreBlock(layout models.StudioLayout, rowIndex int, block []*models.Seat, orphans int) float64 {
+	centreColumn := float64(layout.ColumnCount+1) / 2
+	blockCentre := float64(block[0].ColumnIndex+block[len(block)-1].ColumnIndex) / 2
+	columnScore := math.Abs(blockCentre-centreColumn) / math.Max(centreColumn, 1)
+
+	idealRow := 1 + float64(layout.RowCount-1)*idealRowFraction
+	rowScore := math.Abs(float64(rowIndex)-idealRow) / math.Max(float64(layout.RowCount), 1)
+
+	score := columnScore + rowScore + orphanPenalty*float64(orphans)
+	return math.Round(score*1000) / 1000
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLayout builds a layout from one string per row: "o" is a free seat,
+// "x" a taken one, "w" a free wheelchair space, "c" a free couch seat
+// paired with its neighbour and "|" an aisle.
+func testLayout(rows ...string) models.StudioLayout {
+	studio := models.Studio{ID: 1, Name: "Test", Rows: len(rows)}
+	var seats []models.Seat
+	aisles := map[int]bool{}
+
+	id := uint(0)
+	for r, row := range rows {
+		label := string(rune('A' + r))
+		for c, code := range row {
+			column := c + 1
+			if column > studio.Columns {
+				studio.Columns = column
+			}
+			if code == '|' {
+				if !aisles[column] {
+					aisles[column] = true
+					studio.Aisles = append(studio.Aisles, models.Aisle{StudioID: 1, Column: column})
+				}
+				continue
+			}
+
+			id++
+			seat := models.Seat{
+				ID:          id,
+				SeatNumber:  fmt.Sprintf("%s%d", label, column),
+				RowLabel:    label,
+				RowIndex:    r + 1,
+				ColumnIndex: column,
+				SeatType:    models.SeatTypeStandard,
+				IsAvailable: code != 'x',
+			}
+			switch code {
+			case 'w':
+				seat.SeatType = models.SeatTypeWheelchair
+			case 'c':
+				seat.SeatType = models.SeatTypeCouch
+				if len(seats) > 0 && seats[len(seats)-1].SeatType == models.SeatTypeCouch && seats[len(seats)-1].PairSeatID == nil {
+					partner := seats[len(seats)-1].ID
+					seats[len(seats)-1].PairSeatID = &seat.ID
+					seat.PairSeatID = &partner
+				}
+			}
+			seats = append(seats, seat)
+		}
+	}
+
+	return buildLayout(studio, seats)
+}
+
+func TestSuggestSeatBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     []string
+		count    int
+		seatType string
+		expected [][]string
+		orphans  []int
+	}{
+		{
+			name:     "Prefers the centre of the row",
+			rows:     []string{"oooooooooo"},
+			count:    2,
+			expected: [][]string{{"A5", "A6"}, {"A4", "A5"}, {"A6", "A7"}},
+			orphans:  []int{0, 0, 0},
+		},
+		{
+			name:     "Prefers rows towards the back",
+			rows:     []string{"ooo", "ooo", "ooo"},
+			count:    3,
+			expected: [][]string{{"B1", "B2", "B3"}, {"C1", "C2", "C3"}, {"A1", "A2", "A3"}},
+			orphans:  []int{0, 0, 0},
+		},
+		{
+			name:     "Avoids stranding a single seat",
+			rows:     []string{"xxoooooxx"},
+			count:    2,
+			expected: [][]string{{"A3", "A4"}, {"A6", "A7"}, {"A4", "A5"}},
+			orphans:  []int{0, 0, 1},
+		},
+		{
+			name:     "Offers orphaning blocks when nothing else fits",
+			rows:     []string{"xxxoooxxx"},
+			count:    2,
+			expected: [][]string{{"A4", "A5"}, {"A5", "A6"}},
+			orphans:  []int{1, 1},
+		},
+		{
+			name:  "Does not span an aisle",
+			rows:  []string{"oo|oo"},
+			count: 3,
+		},
+		{
+			name:     "Leaves wheelchair spaces alone by default",
+			rows:     []string{"wwooo"},
+			count:    2,
+			expected: [][]string{{"A4", "A5"}, {"A3", "A4"}},
+			orphans:  []int{0, 1},
+		},
+		{
+			name:     "Finds the requested seat type",
+			rows:     []string{"wwooo"},
+			count:    1,
+			seatType: models.SeatTypeWheelchair,
+			expected: [][]string{{"A1"}, {"A2"}},
+			orphans:  []int{0, 1},
+		},
+		{
+			name:  "Does not split a couch",
+			rows:  []string{"cc"},
+			count: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := suggestSeatBlocks(testLayout(tt.rows...), tt.count, tt.seatType, maxSuggestions)
+
+			var numbers [][]string
+			var orphans []int
+			for _, suggestion := range suggestions {
+				numbers = append(numbers, suggestion.SeatNumbers)
+				orphans = append(orphans, suggestion.OrphanSeats)
+				assert.Len(t, suggestion.SeatIDs, tt.count)
+			}
+			assert.Equal(t, tt.expected, numbers)
+			assert.Equal(t, tt.orphans, orphans)
+		})
+	}
+}
+
+func TestSuggestSeatsRejectsBadInput(t *testing.T) {
+	_, err := SuggestSeats(1, 0, "")
+	assert.ErrorIs(t, err, ErrInvalidSeatCount)
+
+	_, err = SuggestSeats(1, maxSuggestedSeats+1, "")
+	assert.ErrorIs(t, err, ErrInvalidSeatCount)
+
+	_, err = SuggestSeats(1, 2, "balcony")
+	assert.ErrorIs(t, err, ErrInvalidSeatType)
+}
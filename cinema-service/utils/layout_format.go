@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cinema-service/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Studio layouts can be written as a grid of characters, one line per row,
+// starting with the row nearest the screen:
+//
+//	name: Studio 7
+//	A SSSS|SSSS
+//	B SSSS|SSSS
+//	- ....|....
+//	C WCSS|PPLL
+//
+// Each line is a row label followed by one character per grid column:
+//
+//	S standard   P premium   W wheelchair   C companion
+//	L couch (always in pairs)   | aisle   . gap
+//
+// A label of "-" marks a spacer row without seats. Aisles run through the
+// whole room, so a column holding "|" in one row may not hold a seat in
+// another. JSON and YAML documents carry the same name and row lines:
+//
+//	{"name": "Studio 7", "rows": ["A SSSS|SSSS", "B SSSS|SSSS"]}
+
+// Supported layout document formats.
+const (
+	LayoutFormatText = "text"
+	LayoutFormatJSON = "json"
+	LayoutFormatYAML = "yaml"
+)
+
+const (
+	cellAisle  = '|'
+	cellGap    = '.'
+	spacerRow  = "-"
+	nameHeader = "name:"
+)
+
+var seatCodes = map[rune]string{
+	'S': models.SeatTypeStandard,
+	'P': models.SeatTypePremium,
+	'W': models.SeatTypeWheelchair,
+	'C': models.SeatTypeCompanion,
+	'L': models.SeatTypeCouch,
+}
+
+var seatTypeCodes = map[string]rune{
+	models.SeatTypeStandard:   'S',
+	models.SeatTypePremium:    'P',
+	models.SeatTypeWheelchair: 'W',
+	models.SeatTypeCompanion:  'C',
+	models.SeatTypeCouch:      'L',
+}
+
+// LayoutDocument is a studio layout in its declarative form.
+type LayoutDocument struct {
+	Name string   `json:"name" yaml:"name"`
+	Rows []string `json:"rows" yaml:"rows"`
+}
+
+// LayoutProblem points at the place in a layout document that is wrong.
+// Row and Column are 1-based; Line is only set for the text format.
+type LayoutProblem struct {
+	Row     int    `json:"row"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p LayoutProblem) String() string {
+	where := fmt.Sprintf("row %d", p.Row)
+	if p.Line > 0 {
+		where += fmt.Sprintf(" (line %d)", p.Line)
+	}
+	if p.Column > 0 {
+		where += fmt.Sprintf(", column %d", p.Column)
+	}
+	if p.Row == 0 {
+		where = "document"
+	}
+	return where + ": " + p.Message
+}
+
+// LayoutFormatError collects every problem found in a layout document.
+type LayoutFormatError struct {
+	Problems []LayoutProblem
+}
+
+func (e *LayoutFormatError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		messages[i] = p.String()
+	}
+	return "invalid layout document: " + strings.Join(messages, "; ")
+}
+
+// NormalizeLayoutFormat maps a format name or content type to one of the
+// supported formats, defaulting to text.
+func NormalizeLayoutFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if i := strings.Index(format, ";"); i >= 0 {
+		format = strings.TrimSpace(format[:i])
+	}
+
+	switch format {
+	case "", "text", "txt", "text/plain":
+		return LayoutFormatText, nil
+	case "json", "application/json":
+		return LayoutFormatJSON, nil
+	case "yaml", "yml", "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return LayoutFormatYAML, nil
+	}
+	return "", fmt.Errorf("unsupported layout format %q", format)
+}
+
+// ParseLayout reads a layout document and turns it into a studio name and a
+// layout request ready for the studio administration service.
+func ParseLayout(data []byte, format string) (string, *models.StudioLayoutRequest, error) {
+	doc := LayoutDocument{}
+	lines := []int{}
+
+	switch format {
+	case LayoutFormatJSON:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return "", nil, &LayoutFormatError{Problems: []LayoutProblem{{Message: "malformed JSON: " + err.Error()}}}
+		}
+	case LayoutFormatYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return "", nil, &LayoutFormatError{Problems: []LayoutProblem{{Message: "malformed YAML: " + err.Error()}}}
+		}
+	case LayoutFormatText:
+		doc, lines = parseLayoutText(string(data))
+	default:
+		return "", nil, fmt.Errorf("unsupported layout format %q", format)
+	}
+
+	req, problems := parseLayoutRows(doc.Rows, lines)
+	if len(problems) > 0 {
+		return "", nil, &LayoutFormatError{Problems: problems}
+	}
+	return strings.TrimSpace(doc.Name), req, nil
+}
+
+// parseLayoutText splits the text format into a document, remembering the
+// file line each row came from.
+func parseLayoutText(text string) (LayoutDocument, []int) {
+	doc := LayoutDocument{}
+	lines := []int{}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(doc.Rows) == 0 && strings.HasPrefix(strings.ToLower(trimmed), nameHeader) {
+			doc.Name = strings.TrimSpace(trimmed[len(nameHeader):])
+			continue
+		}
+		doc.Rows = append(doc.Rows, trimmed)
+		lines = append(lines, lineNumber)
+	}
+	return doc, lines
+}
+
+func parseLayoutRows(rows []string, lines []int) (*models.StudioLayoutRequest, []LayoutProblem) {
+	var problems []LayoutProblem
+	req := &models.StudioLayoutRequest{}
+
+	if len(rows) == 0 {
+		return nil, []LayoutProblem{{Message: "layout has no rows"}}
+	}
+
+	problemAt := func(row, column int, format string, args ...interface{}) {
+		p := LayoutProblem{Row: row, Column: column, Message: fmt.Sprintf(format, args...)}
+		if row-1 < len(lines) {
+			p.Line = lines[row-1]
+		}
+		problems = append(problems, p)
+	}
+
+	aisleColumns := map[int]bool{}
+	seatColumns := map[int][]int{}
+	labels := map[string]int{}
+
+	for i, raw := range rows {
+		rowIndex := i + 1
+		fields := strings.Fields(raw)
+		if len(fields) != 2 {
+			problemAt(rowIndex, 0, "expected a row label followed by the row's cells")
+			continue
+		}
+		label, cells := fields[0], []rune(fields[1])
+
+		if label != spacerRow {
+			if previous, ok := labels[label]; ok {
+				problemAt(rowIndex, 0, "row label %q is already used by row %d", label, previous)
+			}
+			labels[label] = rowIndex
+		}
+
+		for c := 0; c < len(cells); c++ {
+			column := c + 1
+			code := cells[c]
+
+			switch {
+			case code == cellAisle:
+				aisleColumns[column] = true
+				continue
+			case code == cellGap:
+				continue
+			}
+
+			seatType, ok := seatCodes[code]
+			if !ok {
+				problemAt(rowIndex, column, "unknown cell %q", string(code))
+				continue
+			}
+			if label == spacerRow {
+				problemAt(rowIndex, column, "spacer rows cannot contain seats")
+				continue
+			}
+
+			if seatType == models.SeatTypeCouch {
+				// Couches take two cells; consume the partner now.
+				if c+1 >= len(cells) || cells[c+1] != 'L' {
+					problemAt(rowIndex, column, "couch seat needs a second couch seat on its right")
+					continue
+				}
+				for _, col := range []int{column, column + 1} {
+					req.Seats = append(req.Seats, models.SeatLayoutInput{Row: label, RowIndex: rowIndex, Column: col, SeatType: seatType})
+					seatColumns[col] = append(seatColumns[col], rowIndex)
+				}
+				c++
+				continue
+			}
+
+			req.Seats = append(req.Seats, models.SeatLayoutInput{Row: label, RowIndex: rowIndex, Column: column, SeatType: seatType})
+			seatColumns[column] = append(seatColumns[column], rowIndex)
+		}
+	}
+
+	for column := range aisleColumns {
+		for _, rowIndex := range seatColumns[column] {
+			problemAt(rowIndex, column, "seat sits in a column used as an aisle in another row")
+		}
+		req.AisleColumns = append(req.AisleColumns, column)
+	}
+	sort.Ints(req.AisleColumns)
+
+	if len(req.Seats) == 0 && len(problems) == 0 {
+		problems = append(problems, LayoutProblem{Message: "layout has no seats"})
+	}
+
+	sort.SliceStable(problems, func(a, b int) bool {
+		if problems[a].Row != problems[b].Row {
+			return problems[a].Row < problems[b].Row
+		}
+		return problems[a].Column < problems[b].Column
+	})
+	return req, problems
+}
+
+// ExportLayout writes a studio layout back into a layout document. Custom
+// seat numbers are not kept: seats are renumbered from their position when
+// the document is imported again.
+func ExportLayout(layout models.StudioLayout, format string) ([]byte, error) {
+	doc := LayoutDocument{Name: layout.Name, Rows: make([]string, 0, len(layout.Rows))}
+
+	for _, row := range layout.Rows {
+		var cells strings.Builder
+		hasSeats := false
+		for _, cell := range row.Cells {
+			switch cell.Kind {
+			case models.CellSeat:
+				code, ok := seatTypeCodes[cell.Seat.SeatType]
+				if !ok {
+					code = 'S'
+				}
+				cells.WriteRune(code)
+				hasSeats = true
+			case models.CellAisle:
+				cells.WriteRune(cellAisle)
+			default:
+				cells.WriteRune(cellGap)
+			}
+		}
+
+		label := row.Label
+		if !hasSeats || label == "" {
+			label = spacerRow
+		}
+		doc.Rows = append(doc.Rows, label+" "+cells.String())
+	}
+
+	switch format {
+	case LayoutFormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case LayoutFormatYAML:
+		return yaml.Marshal(doc)
+	case LayoutFormatText:
+		var out strings.Builder
+		out.WriteString(nameHeader + " " + doc.Name + "\n")
+		for _, row := range doc.Rows {
+			out.WriteString(row + "\n")
+		}
+		return []byte(out.String()), nil
+	}
+	return nil, fmt.Errorf("unsupported layout format %q", format)
+}
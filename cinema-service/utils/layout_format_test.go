@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"cinema-service/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleLayout = `# Studio 7, screen at the top
+name: Studio 7
+A SS|SS
+B SS|PP
+
+- ..|..
+C WC|LL
+`
+
+func TestParseLayoutText(t *testing.T) {
+	name, req, err := ParseLayout([]byte(sampleLayout), LayoutFormatText)
+	assert.NoError(t, err)
+	assert.Equal(t, "Studio 7", name)
+	assert.Equal(t, []int{3}, req.AisleColumns)
+	assert.Len(t, req.Seats, 12)
+
+	last := req.Seats[len(req.Seats)-1]
+	assert.Equal(t, models.SeatLayoutInput{Row: "C", RowIndex: 4, Column: 5, SeatType: models.SeatTypeCouch}, last)
+	assert.Equal(t, models.SeatTypeWheelchair, req.Seats[8].SeatType)
+	assert.Equal(t, models.SeatTypeCompanion, req.Seats[9].SeatType)
+}
+
+func TestParseLayoutFormatsAgree(t *testing.T) {
+	jsonDoc := `{"name": "Studio 7", "rows": ["A SS|SS", "B SS|PP", "- ..|..", "C WC|LL"]}`
+	yamlDoc := "name: Studio 7\nrows:\n  - A SS|SS\n  - B SS|PP\n  - \"- ..|..\"\n  - C WC|LL\n"
+
+	_, fromText, err := ParseLayout([]byte(sampleLayout), LayoutFormatText)
+	assert.NoError(t, err)
+
+	name, fromJSON, err := ParseLayout([]byte(jsonDoc), LayoutFormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, "Studio 7", name)
+	assert.Equal(t, fromText, fromJSON)
+
+	name, fromYAML, err := ParseLayout([]byte(yamlDoc), LayoutFormatYAML)
+	assert.NoError(t, err)
+	assert.Equal(t, "Studio 7", name)
+	assert.Equal(t, fromText, fromYAML)
+}
+
+func TestParseLayoutReportsPositions(t *testing.T) {
+	doc := "name: Broken\nA SSXS\nB SL|S\nA SSSS\nD .S|.\n- .S..\n"
+
+	_, _, err := ParseLayout([]byte(doc), LayoutFormatText)
+
+	var invalid *LayoutFormatError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []LayoutProblem{
+		{Row: 1, Line: 2, Column: 3, Message: `unknown cell "X"`},
+		{Row: 2, Line: 3, Column: 2, Message: "couch seat needs a second couch seat on its right"},
+		{Row: 3, Line: 4, Message: `row label "A" is already used by row 1`},
+		{Row: 3, Line: 4, Column: 3, Message: "seat sits in a column used as an aisle in another row"},
+		{Row: 5, Line: 6, Column: 2, Message: "spacer rows cannot contain seats"},
+	}, invalid.Problems)
+	assert.Contains(t, err.Error(), `row 1 (line 2), column 3: unknown cell "X"`)
+}
+
+func TestParseLayoutEmpty(t *testing.T) {
+	_, _, err := ParseLayout([]byte("name: Empty\n"), LayoutFormatText)
+	assert.EqualError(t, err, "invalid layout document: document: layout has no rows")
+
+	_, _, err = ParseLayout([]byte("{not json"), LayoutFormatJSON)
+	assert.Error(t, err)
+}
+
+func TestNormalizeLayoutFormat(t *testing.T) {
+	tests := map[string]string{
+		"":                          LayoutFormatText,
+		"text/plain; charset=utf-8": LayoutFormatText,
+		"JSON":                      LayoutFormatJSON,
+		"application/json":          LayoutFormatJSON,
+		"yml":                       LayoutFormatYAML,
+		"application/x-yaml":        LayoutFormatYAML,
+	}
+	for input, expected := range tests {
+		format, err := NormalizeLayoutFormat(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, format, input)
+	}
+
+	_, err := NormalizeLayoutFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestExportLayoutRoundTrip(t *testing.T) {
+	seat := func(row string, rowIndex, column int, seatType string) *models.Seat {
+		return &models.Seat{RowLabel: row, RowIndex: rowIndex, ColumnIndex: column, SeatType: seatType}
+	}
+	layout := models.StudioLayout{
+		Name: "Studio 7",
+		Rows: []models.LayoutRow{
+			{Index: 1, Label: "A", Cells: []models.LayoutCell{
+				{Column: 1, Kind: models.CellSeat, Seat: seat("A", 1, 1, models.SeatTypePremium)},
+				{Column: 2, Kind: models.CellAisle},
+				{Column: 3, Kind: models.CellSeat, Seat: seat("A", 1, 3, models.SeatTypeStandard)},
+			}},
+			{Index: 2, Cells: []models.LayoutCell{
+				{Column: 1, Kind: models.CellGap},
+				{Column: 2, Kind: models.CellAisle},
+				{Column: 3, Kind: models.CellGap},
+			}},
+		},
+	}
+
+	for _, format := range []string{LayoutFormatText, LayoutFormatJSON, LayoutFormatYAML} {
+		data, err := ExportLayout(layout, format)
+		assert.NoError(t, err)
+
+		name, req, err := ParseLayout(data, format)
+		assert.NoError(t, err, format)
+		assert.Equal(t, "Studio 7", name)
+		assert.Equal(t, []int{2}, req.AisleColumns)
+		assert.Equal(t, []models.SeatLayoutInput{
+			{Row: "A", RowIndex: 1, Column: 1, SeatType: models.SeatTypePremium},
+			{Row: "A", RowIndex: 1, Column: 3, SeatType: models.SeatTypeStandard},
+		}, req.Seats)
+	}
+
+	text, _ := ExportLayout(layout, LayoutFormatText)
+	assert.Equal(t, "name: Studio 7\nA P|S\n- .|.\n", string(text))
+}
@@ -28,6 +28,7 @@ echo [2/4] Testing Cinema Service...
 echo ----------------------------------------
 cd cinema-service
 go mod tidy
+if "%TEST_DATABASE_URL%"=="" echo TEST_DATABASE_URL not set; skipping the PostgreSQL seat reservation tests, so the seat locking is not verified by this run (see README)
 go test -v ./...
 if %ERRORLEVEL% EQU 0 (
     echo ✅ Cinema Service tests PASSED
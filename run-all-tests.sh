@@ -28,6 +28,9 @@ echo "[2/4] Testing Cinema Service..."
 echo "----------------------------------------"
 cd cinema-service
 go mod tidy
+if [ -z "$TEST_DATABASE_URL" ]; then
+    echo "TEST_DATABASE_URL not set; skipping the PostgreSQL seat reservation tests, so the seat locking is not verified by this run (see README)"
+fi
 if go test -v ./...; then
     echo "✅ Cinema Service tests PASSED"
     ((PASSED_TESTS++))
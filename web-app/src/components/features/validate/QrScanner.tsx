@@ -1,14 +1,24 @@
-import { useEffect, useRef, useState } from "react";
+import { useState } from "react";
 import { Scanner } from '@yudiel/react-qr-scanner';
 import { validateBooking } from "../../../shared/api/booking";
-import type { Booking, BookingDetail } from "../../../shared/interfaces/booking";
+import type { Studio } from "../../../shared/interfaces/studio";
 
-const QrScanner = () => {
+const QrScanner = ({ studios, token }: { studios: Studio[]; token: string }) => {
+  const [studioId, setStudioId] = useState<number>(0);
+  const [message, setMessage] = useState<string>("");
+
+  // The QR code holds the signed ticket as text; it is sent as scanned,
+  // together with the studio this entrance belongs to.
   const handleScan = async (result: any) => {
-    console.log(result);
-    const data: { bookingCode: string } = result.length > 0 && JSON.parse(result[0].rawValue);
-    console.log(data);
-    await validateBooking(data.bookingCode || "");
+    const ticket: string = result.length > 0 ? result[0].rawValue : "";
+    if (ticket === "") return;
+    if (studioId === 0) return setMessage("Select the studio first");
+    try {
+      const data = await validateBooking(ticket, studioId, JSON.parse(token || "{}"));
+      setMessage(`Admitted ${data.admittedCount} of ${data.seatCount} seats`);
+    } catch (error: any) {
+      setMessage(error.message);
+    }
   };
 
   const handleError = (error: any) => {
@@ -16,11 +26,24 @@ const QrScanner = () => {
   };
 
   return (
-    <div className="flex flex-col items-center">
+    <div className="flex flex-col items-center gap-4">
+      <select
+        className="border rounded p-2"
+        value={studioId}
+        onChange={(e) => setStudioId(Number(e.target.value))}
+      >
+        <option value={0}>Select studio</option>
+        {studios.map((studio) => (
+          <option key={studio.id} value={studio.id}>
+            {studio.name}
+          </option>
+        ))}
+      </select>
       <Scanner
         onScan={handleScan}
         onError={handleError}
       />
+      {message && <p>{message}</p>}
     </div>
   );
 };
@@ -45,16 +45,18 @@ export const getMyBookings = async (token: UserToken) => {
     throw new Error(response.statusText);
 }    
 
-export const validateBooking = async (bookingCode: string) => { 
+export const validateBooking = async (ticket: string, studioId: number, token: UserToken) => { 
     const response = await fetch(API_ENDPOINT.VALIDATE, {
         headers: {
             'content-type': 'application/json',
+            'Authorization': `Bearer ${token?.token || ""}`,
         },
         method: "POST",
-        body: JSON.stringify({bookingCode}),
+        body: JSON.stringify({ticket, studioId}),
     });
     if (response.ok) {
         return await response.json();
     }
-    throw new Error(response.statusText);
-}
\ No newline at end of file
+    const body = await response.json().catch(() => null);
+    throw new Error(body?.error || response.statusText);
+}
\ No newline at end of file